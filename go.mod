@@ -6,5 +6,5 @@ require (
 	github.com/gdamore/tcell v1.4.0
 	github.com/mattn/go-runewidth v0.0.13 // indirect
 	github.com/olekukonko/tablewriter v0.0.5
-	github.com/veandco/go-sdl2 v0.4.8 // indirect
+	github.com/veandco/go-sdl2 v0.4.8
 )
@@ -0,0 +1,307 @@
+package asm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Origin is the address programs are loaded at
+const Origin = 0x200
+
+// line is a statement of the source with its label references unresolved
+type line struct {
+	number   int
+	mnemonic string
+	operands []string
+	address  int
+}
+
+// Assemble translates source in the syntax of chip8.Disassemble into a rom.
+// Besides instructions it understands labels ending in a colon, comments
+// starting with a semicolon and the data directives DB and DW.
+func Assemble(r io.Reader) ([]byte, error) {
+	labels := map[string]int{}
+	var lines []line
+
+	address := Origin
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		text := scanner.Text()
+		if i := strings.Index(text, ";"); i >= 0 {
+			text = text[:i]
+		}
+		text = strings.TrimSpace(text)
+
+		for {
+			i := strings.Index(text, ":")
+			if i < 0 {
+				break
+			}
+			label := strings.TrimSpace(text[:i])
+			if !isLabel(label) {
+				return nil, fmt.Errorf("line %d: invalid label %q", number, label)
+			}
+			if _, ok := labels[strings.ToLower(label)]; ok {
+				return nil, fmt.Errorf("line %d: label %s defined twice", number, label)
+			}
+			labels[strings.ToLower(label)] = address
+			text = strings.TrimSpace(text[i+1:])
+		}
+		if text == "" {
+			continue
+		}
+
+		l := line{number: number, address: address}
+		fields := strings.SplitN(text, " ", 2)
+		l.mnemonic = strings.ToUpper(fields[0])
+		if len(fields) == 2 {
+			for _, op := range strings.Split(fields[1], ",") {
+				l.operands = append(l.operands, strings.TrimSpace(op))
+			}
+		}
+		lines = append(lines, l)
+		address += l.size()
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var rom []byte
+	for _, l := range lines {
+		b, err := l.encode(labels)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", l.number, err)
+		}
+		rom = append(rom, b...)
+	}
+	return rom, nil
+}
+
+func (l line) size() int {
+	switch l.mnemonic {
+	case "DB":
+		return len(l.operands)
+	case "DW":
+		return len(l.operands) * 2
+	}
+	return 2
+}
+
+func (l line) encode(labels map[string]int) ([]byte, error) {
+	ops := l.operands
+	switch l.mnemonic {
+	case "DB":
+		var b []byte
+		for _, op := range ops {
+			v, err := value(op, labels, 0xFF)
+			if err != nil {
+				return nil, err
+			}
+			b = append(b, byte(v))
+		}
+		return b, nil
+	case "DW":
+		var b []byte
+		for _, op := range ops {
+			v, err := value(op, labels, 0xFFFF)
+			if err != nil {
+				return nil, err
+			}
+			b = append(b, byte(v>>8), byte(v))
+		}
+		return b, nil
+	}
+
+	opcode, err := instruction(l.mnemonic, ops, labels)
+	if err != nil {
+		return nil, err
+	}
+	return []byte{byte(opcode >> 8), byte(opcode)}, nil
+}
+
+// instruction encodes a single instruction
+func instruction(mnemonic string, ops []string, labels map[string]int) (uint16, error) {
+	kinds := make([]string, len(ops))
+	regs := make([]uint16, len(ops))
+	for i, op := range ops {
+		kinds[i] = kind(op)
+		if kinds[i] == "V" {
+			regs[i] = register(op)
+		}
+	}
+	shape := mnemonic + " " + strings.Join(kinds, ",")
+
+	// num parses operand i as a number of at most max
+	num := func(i int, max int) (uint16, error) {
+		v, err := value(ops[i], labels, max)
+		return uint16(v), err
+	}
+
+	xy := func(base uint16) (uint16, error) {
+		return base | regs[0]<<8 | regs[1]<<4, nil
+	}
+	xnn := func(base uint16) (uint16, error) {
+		nn, err := num(1, 0xFF)
+		return base | regs[0]<<8 | nn, err
+	}
+	nnn := func(base uint16, i int) (uint16, error) {
+		v, err := num(i, 0xFFF)
+		return base | v, err
+	}
+
+	switch shape {
+	case "CLS ":
+		return 0x00E0, nil
+	case "RET ":
+		return 0x00EE, nil
+	case "SYS n":
+		return nnn(0x0000, 0)
+	case "JP n":
+		return nnn(0x1000, 0)
+	case "CALL n":
+		return nnn(0x2000, 0)
+	case "SE V,n":
+		return xnn(0x3000)
+	case "SNE V,n":
+		return xnn(0x4000)
+	case "SE V,V":
+		return xy(0x5000)
+	case "LD V,n":
+		return xnn(0x6000)
+	case "ADD V,n":
+		return xnn(0x7000)
+	case "LD V,V":
+		return xy(0x8000)
+	case "OR V,V":
+		return xy(0x8001)
+	case "AND V,V":
+		return xy(0x8002)
+	case "XOR V,V":
+		return xy(0x8003)
+	case "ADD V,V":
+		return xy(0x8004)
+	case "SUB V,V":
+		return xy(0x8005)
+	case "SHR V,V":
+		return xy(0x8006)
+	case "SUBN V,V":
+		return xy(0x8007)
+	case "SHL V,V":
+		return xy(0x800E)
+	case "SHR V", "SHL V":
+		// Without Vy the shift gives the same result with and without the shift quirk
+		regs = append(regs, regs[0])
+		if mnemonic == "SHR" {
+			return xy(0x8006)
+		}
+		return xy(0x800E)
+	case "SNE V,V":
+		return xy(0x9000)
+	case "LD I,n":
+		return nnn(0xA000, 1)
+	case "JP V,n":
+		if regs[0] != 0 {
+			return 0, fmt.Errorf("JP only adds V0")
+		}
+		return nnn(0xB000, 1)
+	case "RND V,n":
+		return xnn(0xC000)
+	case "DRW V,V,n":
+		n, err := num(2, 0xF)
+		return 0xD000 | regs[0]<<8 | regs[1]<<4 | n, err
+	case "SKP V":
+		return 0xE09E | regs[0]<<8, nil
+	case "SKNP V":
+		return 0xE0A1 | regs[0]<<8, nil
+	case "LD V,DT":
+		return 0xF007 | regs[0]<<8, nil
+	case "LD V,K":
+		return 0xF00A | regs[0]<<8, nil
+	case "LD DT,V":
+		return 0xF015 | regs[1]<<8, nil
+	case "LD ST,V":
+		return 0xF018 | regs[1]<<8, nil
+	case "ADD I,V":
+		return 0xF01E | regs[1]<<8, nil
+	case "LD F,V":
+		return 0xF029 | regs[1]<<8, nil
+	case "LD B,V":
+		return 0xF033 | regs[1]<<8, nil
+	case "LD [I],V":
+		return 0xF055 | regs[1]<<8, nil
+	case "LD V,[I]":
+		return 0xF065 | regs[0]<<8, nil
+	}
+	return 0, fmt.Errorf("unknown instruction %s %s", mnemonic, strings.Join(ops, ", "))
+}
+
+// kind classifies an operand as a register V, one of the special operands
+// I, DT, ST, K, F, B and [I], or a number or label n
+func kind(op string) string {
+	upper := strings.ToUpper(op)
+	switch upper {
+	case "I", "DT", "ST", "K", "F", "B", "[I]":
+		return upper
+	}
+	if len(op) == 2 && (op[0] == 'V' || op[0] == 'v') {
+		if _, err := strconv.ParseUint(op[1:], 16, 4); err == nil {
+			return "V"
+		}
+	}
+	return "n"
+}
+
+func register(op string) uint16 {
+	v, _ := strconv.ParseUint(op[1:], 16, 4)
+	return uint16(v)
+}
+
+// value parses a decimal, 0x or # prefixed hexadecimal or 0b prefixed
+// binary number, or looks up a label
+func value(op string, labels map[string]int, max int) (int, error) {
+	if address, ok := labels[strings.ToLower(op)]; ok {
+		if address > max {
+			return 0, fmt.Errorf("address of %s does not fit in %#x", op, max)
+		}
+		return address, nil
+	}
+
+	base, digits := 10, op
+	lower := strings.ToLower(op)
+	switch {
+	case strings.HasPrefix(lower, "0x"):
+		base, digits = 16, op[2:]
+	case strings.HasPrefix(lower, "#"):
+		base, digits = 16, op[1:]
+	case strings.HasPrefix(lower, "0b"):
+		base, digits = 2, op[2:]
+	}
+
+	v, err := strconv.ParseUint(digits, base, 16)
+	if err != nil {
+		if isLabel(op) {
+			return 0, fmt.Errorf("unknown label %s", op)
+		}
+		return 0, fmt.Errorf("invalid number %q", op)
+	}
+	if int(v) > max {
+		return 0, fmt.Errorf("%s does not fit in %#x", op, max)
+	}
+	return int(v), nil
+}
+
+func isLabel(s string) bool {
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		return false
+	}
+	for _, r := range s {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
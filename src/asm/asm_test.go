@@ -0,0 +1,146 @@
+package asm
+
+import (
+	"bytes"
+	"chip8/src/selftest"
+	"strings"
+	"testing"
+)
+
+func TestAssemble(t *testing.T) {
+	source := `
+; Draws the digit in V0
+start:	LD V0, 5
+	LD F, V0
+loop:
+	DRW V1, v2, 5   ; wrap around
+	add V1, 0x08
+	SE VA, #FF
+	JP loop
+	LD I, sprite
+	SHR V3
+	LD [I], VF
+	JP V0, start
+sprite: DB 0b11110000, 0x90
+	DW 0x1234
+`
+	rom, err := Assemble(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{
+		0x60, 0x05,
+		0xF0, 0x29,
+		0xD1, 0x25,
+		0x71, 0x08,
+		0x3A, 0xFF,
+		0x12, 0x04,
+		0xA2, 0x14,
+		0x83, 0x36,
+		0xFF, 0x55,
+		0xB2, 0x00,
+		0xF0, 0x90,
+		0x12, 0x34,
+	}
+	if !bytes.Equal(rom, expected) {
+		t.Errorf("Expected % x, got % x", expected, rom)
+	}
+}
+
+func TestAssemble_Errors(t *testing.T) {
+	for _, c := range []struct {
+		source   string
+		expected string
+	}{
+		{"LD V0, 256", "line 1: 256 does not fit in 0xff"},
+		{"\nJP nowhere", "line 2: unknown label nowhere"},
+		{"MOV V0, V1", "line 1: unknown instruction MOV V0, V1"},
+		{"a:\na: CLS", "line 2: label a defined twice"},
+		{"DRW V0, V1, 16", "line 1: 16 does not fit in 0xf"},
+		{"JP V1, 0x200", "line 1: JP only adds V0"},
+	} {
+		_, err := Assemble(strings.NewReader(c.source))
+		if err == nil || err.Error() != c.expected {
+			t.Errorf("%q: expected error %q, got %v", c.source, c.expected, err)
+		}
+	}
+}
+
+func TestWriteListing(t *testing.T) {
+	rom := []byte{0x22, 0x06, 0x12, 0x02, 0xA2, 0x07, 0x00, 0xEE, 0xFF}
+
+	out := bytes.Buffer{}
+	if err := WriteListing(&out, rom); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "" +
+		"\tCALL L206            ; 0x200  2206\n" +
+		"L202:\n" +
+		"\tJP L202              ; 0x202  1202\n" +
+		"\tLD I, 0x207          ; 0x204  a207\n" +
+		"L206:\n" +
+		"\tRET                  ; 0x206  00ee\n" +
+		"\tDB 0xff              ; 0x208  ff\n"
+	if out.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestWriteCoverageListing(t *testing.T) {
+	rom := []byte{0xA2, 0x06, 0x12, 0x02, 0x00, 0xE0, 0xFF, 0x3C, 0x81}
+	isCode := func(address uint16) bool { return address < 0x204 }
+
+	out := bytes.Buffer{}
+	if err := WriteCoverageListing(&out, rom, isCode); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "" +
+		"\tLD I, L206           ; 0x200  a206\n" +
+		"L202:\n" +
+		"\tJP L202              ; 0x202  1202\n" +
+		"\tDB 0x00, 0xe0        ; 0x204  00 e0\n" +
+		"L206:\n" +
+		"\tDB 0xff, 0x3c, 0x81  ; 0x206  ff 3c 81\n"
+	if out.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	got, err := Assemble(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, rom) {
+		t.Errorf("Expected % x, got % x", rom, got)
+	}
+}
+
+// Every rom has to survive a round trip through the disassembler and assembler
+func TestRoundTrip(t *testing.T) {
+	roms := map[string][]byte{
+		"odd": {0x51, 0x21, 0xF1, 0xFF, 0x00, 0x00, 0x8A, 0xB9, 0x42},
+	}
+	for _, name := range selftest.Roms() {
+		rom, err := selftest.Rom(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		roms[name] = rom
+	}
+
+	for name, rom := range roms {
+		listing := bytes.Buffer{}
+		if err := WriteListing(&listing, rom); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Assemble(&listing)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if !bytes.Equal(got, rom) {
+			t.Errorf("%s: expected % x, got % x", name, rom, got)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package asm
+
+import (
+	"bufio"
+	"chip8/src/chip8"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteListing disassembles rom as loaded at Origin into source that
+// Assemble turns back into the same rom. Jump, call and LD I targets inside
+// the rom get labels. Data is disassembled like code since a static
+// disassembler cannot tell them apart, see WriteCoverageListing.
+func WriteListing(w io.Writer, rom []byte) error {
+	return WriteCoverageListing(w, rom, nil)
+}
+
+// WriteCoverageListing is WriteListing for a rom whose code is known, for
+// example from a coverage map. Bytes isCode rejects are listed as DB and
+// only the instructions it accepts give labels. A nil isCode accepts all.
+func WriteCoverageListing(w io.Writer, rom []byte, isCode func(address uint16) bool) error {
+	end := Origin + len(rom)
+	code := func(address int) bool {
+		return address+1 < end && (isCode == nil || isCode(uint16(address)) && isCode(uint16(address+1)))
+	}
+
+	// Data lines end at targets, so the second split has a line for every
+	// target that is not inside an instruction
+	targets := map[int]bool{}
+	for _, l := range splitListing(rom, code, targets) {
+		if t, ok := target(l.opcode()); ok && l.code && t >= Origin && t < end {
+			targets[t] = true
+		}
+	}
+	lines := splitListing(rom, code, targets)
+	labels := map[int]string{}
+	for _, l := range lines {
+		if targets[l.address] {
+			labels[l.address] = fmt.Sprintf("L%03X", l.address)
+		}
+	}
+
+	b := bufio.NewWriter(w)
+	for _, l := range lines {
+		if label, ok := labels[l.address]; ok {
+			fmt.Fprintf(b, "%s:\n", label)
+		}
+
+		if !l.code {
+			values, hex := make([]string, len(l.bytes)), make([]string, len(l.bytes))
+			for i, v := range l.bytes {
+				values[i], hex[i] = fmt.Sprintf("%#02x", v), fmt.Sprintf("%02x", v)
+			}
+			fmt.Fprintf(b, "\t%-20s ; %#03x  %s\n", "DB "+strings.Join(values, ", "), l.address, strings.Join(hex, " "))
+			continue
+		}
+
+		opcode := l.opcode()
+		text := chip8.Disassemble(opcode)
+		if t, ok := target(opcode); ok && labels[t] != "" {
+			text = strings.Replace(text, fmt.Sprintf("%#03x", t), labels[t], 1)
+		}
+		fmt.Fprintf(b, "\t%-20s ; %#03x  %04x\n", text, l.address, opcode)
+	}
+	return b.Flush()
+}
+
+// listingLine is an instruction or up to four bytes of data
+type listingLine struct {
+	address int
+	bytes   []byte
+	code    bool
+}
+
+func (l listingLine) opcode() uint16 {
+	if len(l.bytes) < 2 {
+		return 0
+	}
+	return uint16(l.bytes[0])<<8 | uint16(l.bytes[1])
+}
+
+// splitListing cuts rom into lines. Data lines end before code and targets.
+func splitListing(rom []byte, code func(address int) bool, targets map[int]bool) []listingLine {
+	var lines []listingLine
+	end := Origin + len(rom)
+	for address := Origin; address < end; {
+		if code(address) {
+			lines = append(lines, listingLine{address, rom[address-Origin : address-Origin+2], true})
+			address += 2
+			continue
+		}
+
+		n := 1
+		for n < 4 && address+n < end && !code(address+n) && !targets[address+n] {
+			n++
+		}
+		lines = append(lines, listingLine{address, rom[address-Origin : address-Origin+n], false})
+		address += n
+	}
+	return lines
+}
+
+// target returns the address used by instructions that refer to memory
+func target(opcode uint16) (int, bool) {
+	switch opcode >> 12 {
+	case 0x1, 0x2, 0xA, 0xB:
+		return int(opcode & 0x0FFF), true
+	}
+	return 0, false
+}
@@ -0,0 +1,259 @@
+package chip8
+
+// handler runs a decoded instruction, PC already points to the next one
+type handler func(cpu *Cpu)
+
+// SetDecodeCache makes Step run instructions from a cache of decoded
+// handlers indexed by address instead of decoding them every time. Writes
+// by Fx33 and Fx55, LoadCode, Reset and Restore invalidate the cache, other
+// code that changes Memory has to call InvalidateCache.
+func (cpu *Cpu) SetDecodeCache(enabled bool) {
+	cpu.cache = nil
+	if enabled {
+		cpu.cache = make([]handler, len(cpu.Memory))
+	}
+}
+
+// InvalidateCache drops all decoded instructions
+func (cpu *Cpu) InvalidateCache() {
+	for i := range cpu.cache {
+		cpu.cache[i] = nil
+	}
+}
+
+// invalidate drops the instructions that include the byte at address
+func (cpu *Cpu) invalidate(address uint16) {
+	if cpu.cache == nil {
+		return
+	}
+	cpu.cache[address] = nil
+	if address > 0 {
+		cpu.cache[address-1] = nil
+	}
+}
+
+// cached returns the handler for the instruction at pc, decoding it first if needed
+func (cpu *Cpu) cached(pc uint16) handler {
+	h := cpu.cache[pc]
+	if h == nil {
+		h = decode(cpu.Memory[pc], cpu.Memory[pc+1])
+		cpu.cache[pc] = h
+	}
+	return h
+}
+
+// decode returns a handler that does the same as execute. Opcodes without a
+// dedicated handler, such as SYS and undefined ones, fall back to execute.
+func decode(i1 uint8, i2 uint8) handler {
+	x, y, n := i1&0x0F, i2>>4, i2&0x0F
+	nnn := uint16(i1&0x0F)<<8 | uint16(i2)
+
+	switch i1 >> 4 {
+	case 0x0:
+		switch {
+		case i1 == 0x00 && i2 == 0xE0:
+			return func(cpu *Cpu) { cpu.display.Clear() }
+		case i1 == 0x00 && i2 == 0xEE:
+			return func(cpu *Cpu) {
+				cpu.SP -= 1
+				cpu.PC = cpu.S[cpu.SP]
+			}
+		}
+	case 0x1:
+		return func(cpu *Cpu) { cpu.PC = nnn }
+	case 0x2:
+		return func(cpu *Cpu) {
+			cpu.S[cpu.SP] = cpu.PC
+			cpu.SP = cpu.SP + 1
+			cpu.PC = nnn
+		}
+	case 0x3:
+		return func(cpu *Cpu) {
+			if cpu.V[x] == i2 {
+				cpu.PC += 2
+			}
+		}
+	case 0x4:
+		return func(cpu *Cpu) {
+			if cpu.V[x] != i2 {
+				cpu.PC += 2
+			}
+		}
+	case 0x5:
+		return func(cpu *Cpu) {
+			if cpu.V[x] == cpu.V[y] {
+				cpu.PC += 2
+			}
+		}
+	case 0x6:
+		return func(cpu *Cpu) { cpu.V[x] = i2 }
+	case 0x7:
+		return func(cpu *Cpu) { cpu.V[x] += i2 }
+	case 0x8:
+		if h := decodeArithmetic(x, y, n); h != nil {
+			return h
+		}
+	case 0x9:
+		return func(cpu *Cpu) {
+			if cpu.V[x] != cpu.V[y] {
+				cpu.PC += 2
+			}
+		}
+	case 0xA:
+		return func(cpu *Cpu) { cpu.I = nnn }
+	case 0xB:
+		return func(cpu *Cpu) {
+			if cpu.Quirks.JumpUsesVx {
+				cpu.PC = nnn + uint16(cpu.V[x])
+			} else {
+				cpu.PC = nnn + uint16(cpu.V[0])
+			}
+		}
+	case 0xC:
+		return func(cpu *Cpu) { cpu.V[x] = cpu.rng.GetRandom() & i2 }
+	case 0xD:
+		return func(cpu *Cpu) {
+			if cpu.display.SetSprite(cpu.V[x], cpu.V[y], cpu.readMemory(cpu.I, uint16(n))) {
+				cpu.V[0x0F] = 1
+			} else {
+				cpu.V[0x0F] = 0
+			}
+		}
+	case 0xE:
+		switch i2 {
+		case 0x9E:
+			return func(cpu *Cpu) {
+				if cpu.keyboard.IsDown(cpu.V[x]) {
+					cpu.PC += 2
+				}
+			}
+		case 0xA1:
+			return func(cpu *Cpu) {
+				if !cpu.keyboard.IsDown(cpu.V[x]) {
+					cpu.PC += 2
+				}
+			}
+		}
+	case 0xF:
+		if h := decodeMisc(x, i2); h != nil {
+			return h
+		}
+	}
+
+	return func(cpu *Cpu) { cpu.execute(i1, i2) }
+}
+
+// decodeArithmetic decodes 8xyn, it returns nil for undefined values of n
+func decodeArithmetic(x uint8, y uint8, n uint8) handler {
+	switch n {
+	case 0x0:
+		return func(cpu *Cpu) { cpu.V[x] = cpu.V[y] }
+	case 0x1:
+		return func(cpu *Cpu) {
+			cpu.V[x] |= cpu.V[y]
+			if cpu.Quirks.VFReset {
+				cpu.V[0x0F] = 0
+			}
+		}
+	case 0x2:
+		return func(cpu *Cpu) {
+			cpu.V[x] &= cpu.V[y]
+			if cpu.Quirks.VFReset {
+				cpu.V[0x0F] = 0
+			}
+		}
+	case 0x3:
+		return func(cpu *Cpu) {
+			cpu.V[x] ^= cpu.V[y]
+			if cpu.Quirks.VFReset {
+				cpu.V[0x0F] = 0
+			}
+		}
+	case 0x4:
+		return func(cpu *Cpu) {
+			result := uint16(cpu.V[x]) + uint16(cpu.V[y])
+			cpu.V[x] = uint8(result)
+			if result >= 0x100 {
+				cpu.V[0x0F] = 1
+			} else {
+				cpu.V[0x0F] = 0
+			}
+		}
+	case 0x5, 0x7:
+		return func(cpu *Cpu) {
+			a, b := cpu.V[x], cpu.V[y]
+			if n == 0x7 {
+				a, b = b, a
+			}
+			cpu.V[x] = a - b
+			if a >= b {
+				cpu.V[0x0F] = 1
+			} else {
+				cpu.V[0x0F] = 0
+			}
+		}
+	case 0x6:
+		return func(cpu *Cpu) {
+			v := cpu.V[x]
+			if cpu.Quirks.ShiftUsesVy {
+				v = cpu.V[y]
+			}
+			cpu.V[x] = v >> 1
+			cpu.V[0x0F] = v & 0x01
+		}
+	case 0xE:
+		return func(cpu *Cpu) {
+			v := cpu.V[x]
+			if cpu.Quirks.ShiftUsesVy {
+				v = cpu.V[y]
+			}
+			cpu.V[x] = v << 1
+			cpu.V[0x0F] = v >> 7
+		}
+	}
+	return nil
+}
+
+// decodeMisc decodes Fxkk, it returns nil for undefined values of kk
+func decodeMisc(x uint8, kk uint8) handler {
+	switch kk {
+	case 0x07:
+		return func(cpu *Cpu) { cpu.V[x] = cpu.DT }
+	case 0x0A:
+		return func(cpu *Cpu) { cpu.waitForKey(x) }
+	case 0x15:
+		return func(cpu *Cpu) { cpu.DT = cpu.V[x] }
+	case 0x18:
+		return func(cpu *Cpu) { cpu.ST = cpu.V[x] }
+	case 0x1E:
+		return func(cpu *Cpu) { cpu.I += uint16(cpu.V[x]) }
+	case 0x29:
+		return func(cpu *Cpu) { cpu.I = uint16(cpu.V[x]) * 5 }
+	case 0x33:
+		return func(cpu *Cpu) {
+			v := cpu.V[x]
+			cpu.writeMemory(cpu.I, v/100)
+			cpu.writeMemory(cpu.I+1, v/10%10)
+			cpu.writeMemory(cpu.I+2, v%10)
+		}
+	case 0x55:
+		return func(cpu *Cpu) {
+			for i := uint8(0); i <= x; i++ {
+				cpu.writeMemory(cpu.I+uint16(i), cpu.V[i])
+			}
+			if cpu.Quirks.LoadStoreIncrementsI {
+				cpu.I += uint16(x) + 1
+			}
+		}
+	case 0x65:
+		return func(cpu *Cpu) {
+			for i := uint8(0); i <= x; i++ {
+				cpu.V[i] = cpu.readMemory(cpu.I+uint16(i), 1)[0]
+			}
+			if cpu.Quirks.LoadStoreIncrementsI {
+				cpu.I += uint16(x) + 1
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package chip8
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+type oddKeys struct{}
+
+func (oddKeys) IsDown(key uint8) bool { return key%2 == 1 }
+
+// faults reports whether the next instruction has to fail: it lies past the
+// end of memory, calls with a full stack, returns with an empty one or
+// accesses memory past the end through I. Random programs run until then.
+func faults(cpu *Cpu) bool {
+	pc := int(cpu.PC)
+	if pc+1 >= len(cpu.Memory) {
+		return true
+	}
+	i1, i2 := cpu.Memory[pc], cpu.Memory[pc+1]
+
+	accessed := 0
+	switch {
+	case i1 == 0x00 && i2 == 0xEE:
+		return cpu.SP == 0
+	case i1>>4 == 0x2:
+		return int(cpu.SP) >= len(cpu.S)
+	case i1>>4 == 0xD:
+		accessed = int(i2 & 0x0F)
+	case i1>>4 == 0xF && i2 == 0x33:
+		accessed = 3
+	case i1>>4 == 0xF && (i2 == 0x55 || i2 == 0x65):
+		accessed = int(i1&0x0F) + 1
+	default:
+		return false
+	}
+	return int(cpu.I)+accessed > len(cpu.Memory)
+}
+
+func TestCpu_DecodeCache_Differential(t *testing.T) {
+	profiles := QuirkProfileNames()
+	for seed := int64(0); seed < 200; seed++ {
+		random := rand.New(rand.NewSource(seed))
+		program := make([]byte, DefaultMemorySize)
+		random.Read(program[0x200:])
+		quirks, _ := QuirkProfile(profiles[int(seed)%len(profiles)])
+
+		var cpus [2]Cpu
+		var displays [2]Framebuffer
+		var traces [2]recordingTracer
+		for i := range cpus {
+			cpus[i] = Cpu{Memory: append([]byte(nil), program...), PC: 0x200, display: &displays[i], keyboard: oddKeys{}, Quirks: quirks}
+			cpus[i].SetRng(NewSeededRng(uint64(seed)))
+			cpus[i].SetTracer(&traces[i])
+		}
+		cpus[1].SetDecodeCache(true)
+
+		for step := 1; step <= 2000; step++ {
+			if faults(&cpus[0]) {
+				break
+			}
+			cpus[0].Step()
+			cpus[1].Step()
+			if diff := CompareState(&cpus[0], &cpus[1]); len(diff) > 0 {
+				t.Fatalf("seed %d step %d: %v differ", seed, step, diff)
+			}
+			if displays[0] != displays[1] {
+				t.Fatalf("seed %d step %d: the screens differ", seed, step)
+			}
+		}
+		if !reflect.DeepEqual(traces[0], traces[1]) {
+			t.Fatalf("seed %d: the traces differ", seed)
+		}
+	}
+}
+
+func TestCpu_DecodeCache_SelfModifying(t *testing.T) {
+	cpu := bootstrapTest([]byte{
+		0xA2, 0x10, // LD I, 0x210
+		0x60, 0x72, // LD V0, 0x72
+		0x61, 0x05, // LD V1, 5
+		0x22, 0x10, // CALL 0x210
+		0xF1, 0x55, // LD [I], V1 turns ADD V2, 1 into ADD V2, 5
+		0x22, 0x10, // CALL 0x210
+		0x12, 0x0C, // JP 0x20C
+		0x00, 0x00,
+		0x72, 0x01, // 0x210: ADD V2, 1
+		0x00, 0xEE, // RET
+	})
+	cpu.SetDecodeCache(true)
+
+	for i := 0; i < 11; i++ {
+		cpu.Step()
+	}
+	if cpu.PC != 0x20C || cpu.V[2] != 6 {
+		t.Errorf("PC=%#x V2=%d, want 0x20C and 6 from the rewritten instruction", cpu.PC, cpu.V[2])
+	}
+}
+
+// benchmarkProgram counts, does arithmetic, stores and loads digits and draws
+var benchmarkProgram = []byte{
+	0x70, 0x01, // 0x200: ADD V0, 1
+	0x81, 0x04, // ADD V1, V0
+	0x82, 0x36, // SHR V2, V3
+	0xA3, 0x00, // LD I, 0x300
+	0xF0, 0x33, // LD B, V0
+	0xF2, 0x65, // LD V2, [I]
+	0xD0, 0x15, // DRW V0, V1, 5
+	0x12, 0x00, // JP 0x200
+}
+
+func benchmarkStep(b *testing.B, cache bool) {
+	cpu := NewCPU(DefaultMemorySize, &Framebuffer{}, nil)
+	copy(cpu.Memory[0x200:], benchmarkProgram)
+	cpu.SetDecodeCache(cache)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cpu.Step()
+	}
+}
+
+func BenchmarkCpu_Step(b *testing.B) {
+	benchmarkStep(b, false)
+}
+
+func BenchmarkCpu_Step_DecodeCache(b *testing.B) {
+	benchmarkStep(b, true)
+}
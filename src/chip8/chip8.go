@@ -17,9 +17,22 @@ type Cpu struct {
 	DT uint8
 	ST uint8
 
+	Quirks Quirks
+
 	rng      RngGenerator
 	display  Display
 	keyboard Keyboard
+
+	waiting     bool
+	waitPressed bool
+	waitKey     uint8
+
+	tracer Tracer
+	traced uint64
+	writes []MemoryWrite
+	reads  []uint16
+
+	cache []handler
 }
 
 func (cpu *Cpu) LoadProgram(program io.Reader) error {
@@ -41,6 +54,11 @@ func (cpu *Cpu) DecrementTimers() {
 	}
 }
 
+// SetRng replaces the random number generator used by Cxkk
+func (cpu *Cpu) SetRng(rng RngGenerator) {
+	cpu.rng = rng
+}
+
 func (cpu *Cpu) LoadCode(program io.Reader, from uint16) error {
 	buffer := make([]byte, 100)
 
@@ -62,38 +80,67 @@ func (cpu *Cpu) LoadCode(program io.Reader, from uint16) error {
 		}
 	}
 
-	fmt.Printf("Loaded %d bytes\r\n", offset)
+	cpu.InvalidateCache()
 
 	return nil
 }
 
-func (cpu *Cpu) LoadInterpreter() {
-	_ = cpu.LoadCode(bytes.NewReader([]byte{
-		0xF0, 0x90, 0x90, 0x90, 0xF0,
+// font holds the sprites of the hex digits 0 to F, five bytes each
+var font = []byte{
+	0xF0, 0x90, 0x90, 0x90, 0xF0,
 
-		0x20, 0x60, 0x20, 0x20, 0x70,
-		0xF0, 0x10, 0xF0, 0x80, 0xF0,
-		0xF0, 0x10, 0xF0, 0x10, 0xF0,
+	0x20, 0x60, 0x20, 0x20, 0x70,
+	0xF0, 0x10, 0xF0, 0x80, 0xF0,
+	0xF0, 0x10, 0xF0, 0x10, 0xF0,
 
-		0x90, 0x90, 0xF0, 0x10, 0x10,
-		0xF0, 0x10, 0x20, 0x40, 0x40,
-		0xF0, 0x80, 0xF0, 0x90, 0xF0,
+	0x90, 0x90, 0xF0, 0x10, 0x10,
+	0xF0, 0x80, 0xF0, 0x10, 0xF0,
+	0xF0, 0x80, 0xF0, 0x90, 0xF0,
 
-		0xF0, 0x10, 0x20, 0x40, 0x40,
-		0xF0, 0x90, 0xF0, 0x90, 0xF0,
-		0xF0, 0x90, 0xF0, 0x10, 0xF0,
+	0xF0, 0x10, 0x20, 0x40, 0x40,
+	0xF0, 0x90, 0xF0, 0x90, 0xF0,
+	0xF0, 0x90, 0xF0, 0x10, 0xF0,
 
-		0xF0, 0x90, 0xF0, 0x90, 0x90,
-		0xE0, 0x90, 0xE0, 0x90, 0xE0,
-		0xF0, 0x80, 0x80, 0x80, 0xF0,
+	0xF0, 0x90, 0xF0, 0x90, 0x90,
+	0xE0, 0x90, 0xE0, 0x90, 0xE0,
+	0xF0, 0x80, 0x80, 0x80, 0xF0,
 
-		0xE0, 0x90, 0x90, 0x90, 0xE0,
-		0xF0, 0x80, 0xF0, 0x80, 0xF0,
-		0xF0, 0x80, 0xF0, 0x80, 0x80,
-	}), 0x0)
+	0xE0, 0x90, 0x90, 0x90, 0xE0,
+	0xF0, 0x80, 0xF0, 0x80, 0xF0,
+	0xF0, 0x80, 0xF0, 0x80, 0x80,
+}
 
+func (cpu *Cpu) LoadInterpreter() {
+	_ = cpu.LoadCode(bytes.NewReader(font), 0x0)
 }
 
+// Reset restarts the program at 0x200 with cleared registers, stack, timers
+// and screen. A soft reset keeps the memory, a hard reset clears everything
+// but the font, so the program has to be loaded again.
+func (cpu *Cpu) Reset(hard bool) {
+	if hard {
+		for i := range cpu.Memory {
+			cpu.Memory[i] = 0
+		}
+		copy(cpu.Memory, font)
+		cpu.InvalidateCache()
+	}
+
+	cpu.V = [0x10]uint8{}
+	cpu.PC = 0x200
+	cpu.SP = 0
+	cpu.S = [16]uint16{}
+	cpu.I = 0
+	cpu.DT = 0
+	cpu.ST = 0
+	cpu.waiting = false
+	cpu.waitPressed = false
+	cpu.display.Clear()
+}
+
+// DefaultMemorySize is the memory size the frontends give the cpu
+const DefaultMemorySize = 0xFFF
+
 func NewCPU(memorySize int16, display Display, keyboard Keyboard) Cpu {
 	if display == nil {
 		display = NoDisplay{}
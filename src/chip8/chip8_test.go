@@ -49,3 +49,48 @@ func TestCpu_decrementTimers(t *testing.T) {
 		t.Errorf("Expected DT to be 37, found %d", cpu.DT)
 	}
 }
+
+func TestSeededRng(t *testing.T) {
+	a := NewSeededRng(1234)
+	b := NewSeededRng(1234)
+	seen := map[uint8]bool{}
+
+	for i := 0; i < 1000; i++ {
+		v := a.GetRandom()
+		if v != b.GetRandom() {
+			t.Fatalf("Expected generators with the same seed to agree at %d", i)
+		}
+		seen[v] = true
+	}
+
+	if len(seen) < 200 {
+		t.Errorf("Expected the generator to cover most byte values, saw %d", len(seen))
+	}
+}
+
+func TestCpu_Reset(t *testing.T) {
+	for _, hard := range []bool{false, true} {
+		display := &Framebuffer{}
+		cpu := NewCPU(0x300, display, nil)
+		_ = cpu.LoadProgram(strings.NewReader("\x60\x05\x22\x06"))
+		cpu.Step()
+		cpu.Step()
+		cpu.I, cpu.DT, cpu.ST = 0x50, 3, 4
+		display.SetPixel(1, 1, true)
+
+		cpu.Reset(hard)
+
+		if cpu.PC != 0x200 || cpu.SP != 0 || cpu.V[0] != 0 || cpu.I != 0 || cpu.DT != 0 || cpu.ST != 0 {
+			t.Errorf("Reset(%t) left PC=%#x SP=%d V0=%d I=%#x DT=%d ST=%d", hard, cpu.PC, cpu.SP, cpu.V[0], cpu.I, cpu.DT, cpu.ST)
+		}
+		if display.GetPixel(1, 1) {
+			t.Errorf("Reset(%t) did not clear the screen", hard)
+		}
+		if program := cpu.Memory[0x200] == 0x60; program == hard {
+			t.Errorf("Reset(%t) kept the program: %t", hard, program)
+		}
+		if cpu.Memory[0] != 0xF0 {
+			t.Errorf("Reset(%t) removed the font", hard)
+		}
+	}
+}
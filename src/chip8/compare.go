@@ -0,0 +1,46 @@
+package chip8
+
+import "fmt"
+
+// CompareState returns the names of everything that differs between the
+// architectural state of a and b, such as "PC", "V3" or "memory[0x300]".
+// Peripherals and quirks are not compared.
+func CompareState(a *Cpu, b *Cpu) []string {
+	var fields []string
+	if a.PC != b.PC {
+		fields = append(fields, "PC")
+	}
+	for i := range a.V {
+		if a.V[i] != b.V[i] {
+			fields = append(fields, fmt.Sprintf("V%X", i))
+		}
+	}
+	if a.I != b.I {
+		fields = append(fields, "I")
+	}
+	if a.SP != b.SP {
+		fields = append(fields, "SP")
+	}
+	for i := range a.S {
+		if a.S[i] != b.S[i] {
+			fields = append(fields, fmt.Sprintf("S[%d]", i))
+		}
+	}
+	if a.DT != b.DT {
+		fields = append(fields, "DT")
+	}
+	if a.ST != b.ST {
+		fields = append(fields, "ST")
+	}
+	if len(a.Memory) != len(b.Memory) {
+		fields = append(fields, "memory size")
+		return fields
+	}
+	for i := range a.Memory {
+		if a.Memory[i] != b.Memory[i] {
+			fields = append(fields, fmt.Sprintf("memory[%#04x]", i))
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,49 @@
+package chip8
+
+const (
+	ScreenWidth  = 64
+	ScreenHeight = 32
+)
+
+// Framebuffer is a Display that only keeps the pixels in memory. It is used
+// for headless runs and as the backing store of other displays.
+type Framebuffer struct {
+	Pixels [ScreenWidth * ScreenHeight]bool
+}
+
+func (f *Framebuffer) Clear() {
+	f.Pixels = [ScreenWidth * ScreenHeight]bool{}
+}
+
+func (f *Framebuffer) GetPixel(x uint8, y uint8) bool {
+	return f.Pixels[pixelLocation(x, y)]
+}
+
+// SetPixel toggles the pixel at x, y when on is set, returning true if a lit pixel was turned off
+func (f *Framebuffer) SetPixel(x uint8, y uint8, on bool) bool {
+	loc := pixelLocation(x, y)
+	c := on && f.Pixels[loc]
+	if on {
+		f.Pixels[loc] = !f.Pixels[loc]
+	}
+	return c
+}
+
+func (f *Framebuffer) SetSprite(x uint8, y uint8, sprite []uint8) bool {
+	c := false
+	for i, row := range sprite {
+		for bit := uint8(0); bit < 8; bit++ {
+			if f.SetPixel(x+bit, y+uint8(i), row&(0x80>>bit) != 0) {
+				c = true
+			}
+		}
+	}
+	return c
+}
+
+func (f *Framebuffer) Render() {
+}
+
+func pixelLocation(x uint8, y uint8) int {
+	return int(x)%ScreenWidth + int(y)%ScreenHeight*ScreenWidth
+}
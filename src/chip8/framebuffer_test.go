@@ -0,0 +1,29 @@
+package chip8
+
+import "testing"
+
+func TestFramebuffer_SetSprite(t *testing.T) {
+	f := Framebuffer{}
+
+	if f.SetSprite(60, 31, []uint8{0xFF, 0x81}) {
+		t.Errorf("Expected no collision on an empty screen")
+	}
+
+	for x := uint8(0); x < 8; x++ {
+		if !f.GetPixel((60+x)%ScreenWidth, 31) {
+			t.Errorf("Expected pixel %d,31 to be set", (60+x)%ScreenWidth)
+		}
+	}
+
+	if !f.GetPixel(60, 0) || !f.GetPixel(3, 0) || f.GetPixel(61, 0) {
+		t.Errorf("Expected the second row to wrap around to the top")
+	}
+
+	if !f.SetSprite(60, 31, []uint8{0x80}) {
+		t.Errorf("Expected a collision when drawing over a lit pixel")
+	}
+
+	if f.GetPixel(60, 31) {
+		t.Errorf("Expected the pixel to be toggled off")
+	}
+}
@@ -0,0 +1,66 @@
+package chip8
+
+// SkipIdle runs up to n instructions at once when the cpu is in a loop that
+// cannot end before the timers tick next: a jump to itself or a loop that
+// waits for the delay timer, such as
+//
+//	loop: LD Vx, DT
+//	      SE Vx, 0
+//	      JP loop
+//
+// It returns the number of instructions run, 0 if the cpu is not idle. The
+// state and the trace afterwards are the same as after running the
+// instructions one by one.
+func (cpu *Cpu) SkipIdle(n int) int {
+	length, x := cpu.idleLoop()
+	if length == 0 || n <= 0 {
+		return 0
+	}
+
+	start := cpu.PC
+	if length == 3 {
+		// LD Vx, DT runs first and the timer does not change before the tick
+		cpu.V[x] = cpu.DT
+	}
+	if cpu.tracer != nil {
+		for i := 0; i < n; i++ {
+			pc := start + uint16(2*(i%length))
+			cpu.trace(pc, cpu.Memory[pc], cpu.Memory[pc+1])
+		}
+	}
+	cpu.PC = start + uint16(2*(n%length))
+	return n
+}
+
+// idleLoop returns the number of instructions of the idle loop starting at
+// PC and the register of its LD Vx, DT, or 0 if there is none
+func (cpu *Cpu) idleLoop() (int, uint8) {
+	pc := int(cpu.PC)
+	m := cpu.Memory
+	if pc+1 >= len(m) {
+		return 0, 0
+	}
+	jumpBack := uint16(0x1000 | pc)
+	if uint16(m[pc])<<8|uint16(m[pc+1]) == jumpBack {
+		return 1, 0
+	}
+
+	// LD Vx, DT; SE or SNE Vx, NN; JP back to the LD
+	if pc+5 >= len(m) || m[pc]&0xF0 != 0xF0 || m[pc+1] != 0x07 {
+		return 0, 0
+	}
+	x := m[pc] & 0x0F
+	skip, nn := m[pc+2], m[pc+3]
+	if skip != 0x30|x && skip != 0x40|x {
+		return 0, 0
+	}
+	if uint16(m[pc+4])<<8|uint16(m[pc+5]) != jumpBack {
+		return 0, 0
+	}
+
+	// SE leaves the loop once DT is NN, SNE as long as it is not
+	if (skip&0xF0 == 0x30) == (cpu.DT == nn) {
+		return 0, 0
+	}
+	return 3, x
+}
@@ -0,0 +1,75 @@
+package chip8
+
+import (
+	"reflect"
+	"testing"
+)
+
+type recordingTracer []TraceEntry
+
+func (r *recordingTracer) Trace(entry TraceEntry) {
+	*r = append(*r, entry)
+}
+
+// idleRom waits for the delay timer with SE and with SNE and ends in a jump to itself
+var idleRom = []byte{
+	0x60, 0x05, // LD V0, 5
+	0xF0, 0x15, // LD DT, V0
+	0xF1, 0x07, // 0x204: LD V1, DT
+	0x31, 0x00, // SE V1, 0
+	0x12, 0x04, // JP 0x204
+	0x60, 0x03, // LD V0, 3
+	0xF0, 0x15, // LD DT, V0
+	0xF2, 0x07, // 0x20E: LD V2, DT
+	0x42, 0x03, // SNE V2, 3
+	0x12, 0x0E, // JP 0x20E
+	0x12, 0x14, // 0x214: JP 0x214
+}
+
+// runIdleRom runs frames of 7 instructions, skipping idle loops if skip is set
+func runIdleRom(skip bool) (*Cpu, recordingTracer, int) {
+	cpu := bootstrapTest(idleRom)
+	var trace recordingTracer
+	cpu.SetTracer(&trace)
+
+	skipped := 0
+	for frame := 0; frame < 20; frame++ {
+		for step := 0; step < 7; {
+			n := 0
+			if skip {
+				n = cpu.SkipIdle(7 - step)
+				skipped += n
+			}
+			if n == 0 {
+				cpu.Step()
+				n = 1
+			}
+			step += n
+		}
+		cpu.DecrementTimers()
+	}
+	return &cpu, trace, skipped
+}
+
+func TestCpu_SkipIdle(t *testing.T) {
+	plain, plainTrace, _ := runIdleRom(false)
+	skipping, skippingTrace, skipped := runIdleRom(true)
+
+	if skipped < 100 {
+		t.Errorf("only %d instructions were skipped", skipped)
+	}
+	if diff := CompareState(plain, skipping); len(diff) > 0 {
+		t.Errorf("state differs in %v", diff)
+	}
+	if plain.PC != 0x214 {
+		t.Errorf("PC = %#x, want the final loop at 0x214", plain.PC)
+	}
+	if !reflect.DeepEqual(plainTrace, skippingTrace) {
+		for i := range plainTrace {
+			if i >= len(skippingTrace) || !reflect.DeepEqual(plainTrace[i], skippingTrace[i]) {
+				t.Fatalf("traces differ from step %d", i+1)
+			}
+		}
+		t.Fatalf("trace has %d entries instead of %d", len(skippingTrace), len(plainTrace))
+	}
+}
@@ -18,5 +18,8 @@ type Display interface {
 
 type Keyboard interface {
 	IsDown(key uint8) bool
-	WaitForKey() uint8
+}
+
+type Tracer interface {
+	Trace(entry TraceEntry)
 }
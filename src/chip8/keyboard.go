@@ -1,14 +1,7 @@
 package chip8
 
 type TestKeyboard struct {
-	KeysDown  []uint8
-	WaitFor   uint8
-	HasWaited bool
-}
-
-func (t *TestKeyboard) WaitForKey() uint8 {
-	t.HasWaited = true
-	return t.WaitFor
+	KeysDown []uint8
 }
 
 func (t *TestKeyboard) IsDown(key uint8) bool {
@@ -28,6 +21,33 @@ func (n NoKeyboard) IsDown(_ uint8) bool {
 	return false
 }
 
-func (n NoKeyboard) WaitForKey() uint8 {
-	return 0
+// WaitingForKey reports whether the cpu is stuck on Fx0A
+func (cpu *Cpu) WaitingForKey() bool {
+	return cpu.waiting
+}
+
+// waitForKey implements Fx0A the way the COSMAC VIP did: the instruction
+// is executed again until a key has been pressed and released, so timers and
+// the display keep running meanwhile.
+func (cpu *Cpu) waitForKey(x uint8) {
+	if !cpu.waiting {
+		cpu.waiting = true
+		cpu.waitPressed = false
+	}
+
+	if !cpu.waitPressed {
+		for key := uint8(0); key < 0x10; key++ {
+			if cpu.keyboard.IsDown(key) {
+				cpu.waitKey = key
+				cpu.waitPressed = true
+				break
+			}
+		}
+	} else if !cpu.keyboard.IsDown(cpu.waitKey) {
+		cpu.V[x] = cpu.waitKey
+		cpu.waiting = false
+		return
+	}
+
+	cpu.PC -= 2
 }
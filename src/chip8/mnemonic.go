@@ -0,0 +1,134 @@
+package chip8
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OpcodeClass names the instruction family of opcode, e.g. "DRW" or "LD Vx, DT"
+func OpcodeClass(opcode uint16) string {
+	nn := opcode & 0x00FF
+	switch opcode >> 12 {
+	case 0x0:
+		switch opcode {
+		case 0x00E0:
+			return "CLS"
+		case 0x00EE:
+			return "RET"
+		}
+		return "SYS"
+	case 0x1:
+		return "JP"
+	case 0x2:
+		return "CALL"
+	case 0x3:
+		return "SE Vx, byte"
+	case 0x4:
+		return "SNE Vx, byte"
+	case 0x5:
+		return "SE Vx, Vy"
+	case 0x6:
+		return "LD Vx, byte"
+	case 0x7:
+		return "ADD Vx, byte"
+	case 0x8:
+		switch opcode & 0x000F {
+		case 0x0:
+			return "LD Vx, Vy"
+		case 0x1:
+			return "OR"
+		case 0x2:
+			return "AND"
+		case 0x3:
+			return "XOR"
+		case 0x4:
+			return "ADD Vx, Vy"
+		case 0x5:
+			return "SUB"
+		case 0x6:
+			return "SHR"
+		case 0x7:
+			return "SUBN"
+		case 0xE:
+			return "SHL"
+		}
+	case 0x9:
+		return "SNE Vx, Vy"
+	case 0xA:
+		return "LD I, addr"
+	case 0xB:
+		return "JP V0, addr"
+	case 0xC:
+		return "RND"
+	case 0xD:
+		return "DRW"
+	case 0xE:
+		switch nn {
+		case 0x9E:
+			return "SKP"
+		case 0xA1:
+			return "SKNP"
+		}
+	case 0xF:
+		switch nn {
+		case 0x07:
+			return "LD Vx, DT"
+		case 0x0A:
+			return "LD Vx, K"
+		case 0x15:
+			return "LD DT, Vx"
+		case 0x18:
+			return "LD ST, Vx"
+		case 0x1E:
+			return "ADD I, Vx"
+		case 0x29:
+			return "LD F, Vx"
+		case 0x33:
+			return "LD B, Vx"
+		case 0x55:
+			return "LD [I], Vx"
+		case 0x65:
+			return "LD Vx, [I]"
+		}
+	}
+
+	return "UNKNOWN"
+}
+
+// Disassemble returns the instruction opcode in the assembly syntax of
+// Cowgod's reference, e.g. "DRW V0, V1, 5". Opcodes that are not
+// instructions are written as data, e.g. "DW 0x5121".
+func Disassemble(opcode uint16) string {
+	x, y := opcode>>8&0xF, opcode>>4&0xF
+	nnn, nn, n := opcode&0x0FFF, opcode&0x00FF, opcode&0x000F
+	class := OpcodeClass(opcode)
+	if (class == "SE Vx, Vy" || class == "SNE Vx, Vy") && n != 0 {
+		class = "UNKNOWN"
+	}
+
+	switch class {
+	case "CLS", "RET":
+		return class
+	case "SYS", "JP", "CALL":
+		return fmt.Sprintf("%s %#03x", class, nnn)
+	case "SE Vx, byte", "SNE Vx, byte", "LD Vx, byte", "ADD Vx, byte":
+		return fmt.Sprintf("%s V%X, %#02x", strings.Fields(class)[0], x, nn)
+	case "SE Vx, Vy", "SNE Vx, Vy", "LD Vx, Vy", "ADD Vx, Vy":
+		return fmt.Sprintf("%s V%X, V%X", strings.Fields(class)[0], x, y)
+	case "OR", "AND", "XOR", "SUB", "SHR", "SUBN", "SHL":
+		return fmt.Sprintf("%s V%X, V%X", class, x, y)
+	case "LD I, addr":
+		return fmt.Sprintf("LD I, %#03x", nnn)
+	case "JP V0, addr":
+		return fmt.Sprintf("JP V0, %#03x", nnn)
+	case "RND":
+		return fmt.Sprintf("RND V%X, %#02x", x, nn)
+	case "DRW":
+		return fmt.Sprintf("DRW V%X, V%X, %d", x, y, n)
+	case "SKP", "SKNP":
+		return fmt.Sprintf("%s V%X", class, x)
+	case "LD Vx, DT", "LD Vx, K", "LD Vx, [I]", "LD DT, Vx", "LD ST, Vx", "ADD I, Vx", "LD F, Vx", "LD B, Vx", "LD [I], Vx":
+		return strings.Replace(class, "Vx", fmt.Sprintf("V%X", x), 1)
+	}
+	return fmt.Sprintf("DW %#04x", opcode)
+}
@@ -0,0 +1,31 @@
+package chip8
+
+import "testing"
+
+func TestDisassemble(t *testing.T) {
+	for _, c := range []struct {
+		opcode   uint16
+		expected string
+	}{
+		{0x00E0, "CLS"},
+		{0x0123, "SYS 0x123"},
+		{0x1206, "JP 0x206"},
+		{0x4AFF, "SNE VA, 0xff"},
+		{0x5120, "SE V1, V2"},
+		{0x5121, "DW 0x5121"},
+		{0x8126, "SHR V1, V2"},
+		{0x8128, "DW 0x8128"},
+		{0xA050, "LD I, 0x050"},
+		{0xB300, "JP V0, 0x300"},
+		{0xD125, "DRW V1, V2, 5"},
+		{0xE1A1, "SKNP V1"},
+		{0xF10A, "LD V1, K"},
+		{0xF155, "LD [I], V1"},
+		{0xF165, "LD V1, [I]"},
+		{0xF1FF, "DW 0xf1ff"},
+	} {
+		if got := Disassemble(c.opcode); got != c.expected {
+			t.Errorf("%04x: expected %q, got %q", c.opcode, c.expected, got)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package chip8
+
+import "sort"
+
+// Quirks toggles behaviour that differs between CHIP-8 interpreters.
+// The zero value matches what this emulator has always done.
+type Quirks struct {
+	// ShiftUsesVy makes 8xy6 and 8xyE shift Vy into Vx instead of shifting Vx in place
+	ShiftUsesVy bool
+	// LoadStoreIncrementsI makes Fx55 and Fx65 leave I pointing past the last register
+	LoadStoreIncrementsI bool
+	// JumpUsesVx makes Bnnn jump to nnn + Vx, where x is the highest nibble of nnn
+	JumpUsesVx bool
+	// VFReset makes 8xy1, 8xy2 and 8xy3 clear VF
+	VFReset bool
+}
+
+// QuirkProfiles are the named quirk sets of well known interpreters
+var QuirkProfiles = map[string]Quirks{
+	"default": {},
+	"cosmac": {
+		ShiftUsesVy:          true,
+		LoadStoreIncrementsI: true,
+		VFReset:              true,
+	},
+	"schip": {
+		JumpUsesVx: true,
+	},
+}
+
+// QuirkProfile looks up a profile in QuirkProfiles
+func QuirkProfile(name string) (Quirks, bool) {
+	q, ok := QuirkProfiles[name]
+	return q, ok
+}
+
+// QuirkProfileNames returns the names of all profiles in alphabetical order
+func QuirkProfileNames() []string {
+	names := make([]string, 0, len(QuirkProfiles))
+	for name := range QuirkProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
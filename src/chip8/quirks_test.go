@@ -0,0 +1,71 @@
+package chip8
+
+import "testing"
+
+func Test_Quirk_ShiftUsesVy(t *testing.T) {
+	cpu := bootstrapTest([]byte{0x80, 0x16})
+	cpu.V[0] = 0x03
+	cpu.V[1] = 0x04
+	cpu.Quirks.ShiftUsesVy = true
+	cpu.Step()
+
+	if cpu.V[0] != 0x02 {
+		t.Errorf("Expected V0 to be Vy >> 1 = 0x02, was %#02x", cpu.V[0])
+	}
+	testRestRegister(cpu, false, t)
+}
+
+func Test_Quirk_LoadStoreIncrementsI(t *testing.T) {
+	cpu := bootstrapTest([]byte{0xF3, 0x55, 0xF3, 0x65, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+	cpu.I = 0x204
+	cpu.Quirks.LoadStoreIncrementsI = true
+
+	cpu.Step()
+	if cpu.I != 0x208 {
+		t.Errorf("Expected I to be 0x208 after Fx55, was %#04x", cpu.I)
+	}
+
+	cpu.Step()
+	if cpu.I != 0x20C {
+		t.Errorf("Expected I to be 0x20C after Fx65, was %#04x", cpu.I)
+	}
+}
+
+func Test_Quirk_JumpUsesVx(t *testing.T) {
+	cpu := bootstrapTest([]byte{0xB3, 0x00})
+	cpu.V[0] = 0x01
+	cpu.V[3] = 0x10
+	cpu.Quirks.JumpUsesVx = true
+	cpu.Step()
+
+	if cpu.PC != 0x310 {
+		t.Errorf("Expected PC to be 0x310, was %#04x", cpu.PC)
+	}
+}
+
+func Test_Quirk_VFReset(t *testing.T) {
+	for _, op := range []uint8{0x11, 0x12, 0x13} {
+		cpu := bootstrapTest([]byte{0x80, op})
+		cpu.V[0x0F] = 1
+		cpu.Quirks.VFReset = true
+		cpu.Step()
+
+		testRestRegister(cpu, false, t)
+	}
+}
+
+func TestCompareState(t *testing.T) {
+	a := bootstrapTest([]byte{0x00, 0x00})
+	b := cloneProcessor(a)
+
+	if fields := CompareState(&a, &b); len(fields) != 0 {
+		t.Errorf("Expected no differences, got %v", fields)
+	}
+
+	b.V[0xA] = 1
+	b.Memory[0x201] = 1
+	fields := CompareState(&a, &b)
+	if len(fields) != 2 || fields[0] != "VA" || fields[1] != "memory[0x0201]" {
+		t.Errorf("Expected VA and memory[0x201] to differ, got %v", fields)
+	}
+}
@@ -6,7 +6,27 @@ type rngGenerator struct {
 }
 
 func (r rngGenerator) GetRandom() uint8 {
-	return uint8(rand.Intn(8))
+	return uint8(rand.Intn(0x100))
+}
+
+// SeededRng is a deterministic RngGenerator. Its whole state is State, so
+// copying it is enough to replay the same sequence.
+type SeededRng struct {
+	State uint64
+}
+
+func NewSeededRng(seed uint64) *SeededRng {
+	return &SeededRng{State: seed}
+}
+
+// GetRandom advances the state using splitmix64
+func (r *SeededRng) GetRandom() uint8 {
+	r.State += 0x9E3779B97F4A7C15
+	z := r.State
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	z = z ^ (z >> 31)
+	return uint8(z >> 56)
 }
 
 type rngGeneratorMock struct {
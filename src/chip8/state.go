@@ -0,0 +1,74 @@
+package chip8
+
+import "fmt"
+
+// State is everything about the cpu a program can observe. Peripherals,
+// quirks and the tracer are not part of it.
+type State struct {
+	Memory []uint8
+	V      [0x10]uint8
+	PC     uint16
+	SP     uint16
+	S      [16]uint16
+	I      uint16
+	DT     uint8
+	ST     uint8
+
+	Waiting     bool
+	WaitPressed bool
+	WaitKey     uint8
+
+	// Rng is the state of a SeededRng, other generators cannot be restored
+	Rng uint64
+}
+
+// Snapshot copies the state of the cpu
+func (cpu *Cpu) Snapshot() State {
+	s := State{
+		Memory:      append([]uint8(nil), cpu.Memory...),
+		V:           cpu.V,
+		PC:          cpu.PC,
+		SP:          cpu.SP,
+		S:           cpu.S,
+		I:           cpu.I,
+		DT:          cpu.DT,
+		ST:          cpu.ST,
+		Waiting:     cpu.waiting,
+		WaitPressed: cpu.waitPressed,
+		WaitKey:     cpu.waitKey,
+	}
+	if rng, ok := cpu.rng.(*SeededRng); ok {
+		s.Rng = rng.State
+	}
+	return s
+}
+
+// Restore puts the cpu back into a state taken by Snapshot. The memory sizes
+// have to match.
+func (cpu *Cpu) Restore(s State) error {
+	if len(s.Memory) != len(cpu.Memory) {
+		return fmt.Errorf("state has %d bytes of memory, the cpu has %d", len(s.Memory), len(cpu.Memory))
+	}
+
+	// Only the instructions that changed have to be decoded again
+	for i, b := range s.Memory {
+		if cpu.Memory[i] != b {
+			cpu.Memory[i] = b
+			cpu.invalidate(uint16(i))
+		}
+	}
+	cpu.V = s.V
+	cpu.PC = s.PC
+	cpu.SP = s.SP
+	cpu.S = s.S
+	cpu.I = s.I
+	cpu.DT = s.DT
+	cpu.ST = s.ST
+	cpu.waiting = s.Waiting
+	cpu.waitPressed = s.WaitPressed
+	cpu.waitKey = s.WaitKey
+	if rng, ok := cpu.rng.(*SeededRng); ok {
+		rng.State = s.Rng
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package chip8
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCpu_SnapshotRestore(t *testing.T) {
+	// RND V0, 0xFF; LD I, 0x300; LD [I], V0; JP 0x200
+	program := "\xC0\xFF\xA3\x00\xF0\x55\x12\x00"
+	cpu := NewCPU(0x400, NoDisplay{}, nil)
+	cpu.SetRng(NewSeededRng(7))
+	_ = cpu.LoadProgram(strings.NewReader(program))
+	cpu.Step()
+	cpu.DT = 9
+
+	s := cpu.Snapshot()
+	for i := 0; i < 8; i++ {
+		cpu.Step()
+	}
+	after := cpu.Snapshot()
+
+	if err := cpu.Restore(s); err != nil {
+		t.Fatal(err)
+	}
+	if cpu.Memory[0x300] != 0 || cpu.PC != 0x202 || cpu.DT != 9 {
+		t.Errorf("Restore left memory[0x300]=%#x PC=%#x DT=%d", cpu.Memory[0x300], cpu.PC, cpu.DT)
+	}
+
+	// The random numbers repeat after restoring
+	for i := 0; i < 8; i++ {
+		cpu.Step()
+	}
+	again := NewCPU(0x400, NoDisplay{}, nil)
+	_ = again.Restore(after)
+	if diff := CompareState(&cpu, &again); len(diff) > 0 {
+		t.Errorf("replay differs in %v", diff)
+	}
+	if cpu.Snapshot().Rng != after.Rng {
+		t.Errorf("rng state %d, want %d", cpu.Snapshot().Rng, after.Rng)
+	}
+
+	small := NewCPU(0x300, NoDisplay{}, nil)
+	if err := small.Restore(s); err == nil {
+		t.Error("restoring into a smaller memory succeeded")
+	}
+}
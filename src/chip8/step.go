@@ -14,8 +14,30 @@ func (cpu *Cpu) NextInstruction() (uint8, uint8) {
 }
 
 func (cpu *Cpu) Step() {
+	pc := cpu.PC
+	var i1, i2 uint8
+	if cpu.cache != nil {
+		i1, i2 = cpu.Memory[pc], cpu.Memory[pc+1]
+		cpu.PC += 2
+		cpu.cached(pc)(cpu)
+	} else {
+		i1, i2 = cpu.NextInstruction()
+		cpu.execute(i1, i2)
+	}
 
-	i1, i2 := cpu.NextInstruction()
+	if cpu.tracer != nil {
+		cpu.trace(pc, i1, i2)
+	}
+}
+
+// Execute runs the instruction i1 i2 as if Step had fetched it, so PC has to
+// point past it already. Recompiled code uses it for the instructions it
+// leaves to the interpreter.
+func (cpu *Cpu) Execute(i1 uint8, i2 uint8) {
+	cpu.execute(i1, i2)
+}
+
+func (cpu *Cpu) execute(i1 uint8, i2 uint8) {
 	instruction := (uint16(i1) << 8) | uint16(i2)
 
 	// JMP 1 instruction
@@ -80,63 +102,72 @@ func (cpu *Cpu) Step() {
 
 	if instruction >= 0x8000 && instruction < 0x9000 && (i2<<4 == 0x10) {
 		cpu.V[i1-0x80] = cpu.V[i1-0x80] | cpu.V[i2>>4]
+		if cpu.Quirks.VFReset {
+			cpu.V[0x0F] = 0
+		}
 		return
 	}
 
 	if instruction >= 0x8000 && instruction < 0x9000 && (i2<<4 == 0x20) {
 		cpu.V[i1-0x80] = cpu.V[i1-0x80] & cpu.V[i2>>4]
+		if cpu.Quirks.VFReset {
+			cpu.V[0x0F] = 0
+		}
 		return
 	}
 	if instruction >= 0x8000 && instruction < 0x9000 && (i2<<4 == 0x30) {
 		cpu.V[i1-0x80] = cpu.V[i1-0x80] ^ cpu.V[i2>>4]
+		if cpu.Quirks.VFReset {
+			cpu.V[0x0F] = 0
+		}
 		return
 	}
+	// VF is written after Vx for the arithmetic instructions so the flag wins when x is F
 	if instruction >= 0x8000 && instruction < 0x9000 && (i2<<4 == 0x40) {
 		result := uint16(cpu.V[i1-0x80]) + uint16(cpu.V[i2>>4])
+		cpu.V[i1-0x80] = uint8(result)
 		if result >= 0x100 {
 			cpu.V[0x0F] = 1
 		} else {
 			cpu.V[0x0F] = 0
 		}
-		cpu.V[i1-0x80] = uint8(result)
 		return
 	}
 
 	if instruction >= 0x8000 && instruction < 0x9000 && ((i2<<4 == 0x50) || (i2<<4 == 0x70)) {
-		result := uint16(cpu.V[i1-0x80]) - uint16(cpu.V[i2>>4])
-
-		var n uint8 = 0
+		a, b := cpu.V[i1-0x80], cpu.V[i2>>4]
 		if i2<<4 == 0x70 {
-			n = 1
+			a, b = b, a
 		}
 
-		if cpu.V[i1-0x80] > cpu.V[i2>>4] {
-
-			cpu.V[0x0F] = 1 - n
+		cpu.V[i1-0x80] = a - b
+		if a >= b {
+			cpu.V[0x0F] = 1
 		} else {
-			cpu.V[0x0F] = 0 + n
+			cpu.V[0x0F] = 0
 		}
-		cpu.V[i1-0x80] = uint8(result)
 		return
 	}
 
 	if instruction >= 0x8000 && instruction < 0x9000 && (i2<<4 == 0x60) {
 		x := cpu.V[i1-0x80]
-		cpu.V[0x0F] = x & 0x01
+		if cpu.Quirks.ShiftUsesVy {
+			x = cpu.V[i2>>4]
+		}
 
 		cpu.V[i1-0x80] = x >> 1
+		cpu.V[0x0F] = x & 0x01
 		return
 	}
 
 	if instruction >= 0x8000 && instruction < 0x9000 && (i2<<4 == 0xE0) {
 		x := cpu.V[i1-0x80]
-		if x&0x80 == 0x80 {
-			cpu.V[0x0F] = 1
-		} else {
-			cpu.V[0x0F] = 0
+		if cpu.Quirks.ShiftUsesVy {
+			x = cpu.V[i2>>4]
 		}
 
 		cpu.V[i1-0x80] = x << 1
+		cpu.V[0x0F] = x >> 7
 		return
 	}
 
@@ -153,7 +184,11 @@ func (cpu *Cpu) Step() {
 	}
 
 	if instruction >= 0xB000 && instruction < 0xC000 {
-		cpu.PC = instruction - 0xB000 + uint16(cpu.V[0])
+		if cpu.Quirks.JumpUsesVx {
+			cpu.PC = instruction - 0xB000 + uint16(cpu.V[i1-0xB0])
+		} else {
+			cpu.PC = instruction - 0xB000 + uint16(cpu.V[0])
+		}
 		return
 	}
 
@@ -164,7 +199,11 @@ func (cpu *Cpu) Step() {
 
 	if instruction >= 0xD000 && instruction < 0xE000 {
 		n := i2 & 0x0F
-		cpu.display.SetSprite(cpu.V[i1-0xD0], cpu.V[i2>>4], cpu.Memory[cpu.I:cpu.I+uint16(n)])
+		if cpu.display.SetSprite(cpu.V[i1-0xD0], cpu.V[i2>>4], cpu.readMemory(cpu.I, uint16(n))) {
+			cpu.V[0x0F] = 1
+		} else {
+			cpu.V[0x0F] = 0
+		}
 		return
 	}
 
@@ -192,7 +231,7 @@ func (cpu *Cpu) Step() {
 	}
 
 	if instruction > 0xF000 && i2 == 0x0A {
-		cpu.V[i1-0xF0] = cpu.keyboard.WaitForKey()
+		cpu.waitForKey(i1 - 0xF0)
 		return
 	}
 
@@ -205,7 +244,7 @@ func (cpu *Cpu) Step() {
 	}
 
 	if instruction > 0xF000 && i2 == 0x29 {
-		cpu.I = uint16(cpu.V[i1-0xF0]) * 4
+		cpu.I = uint16(cpu.V[i1-0xF0]) * 5
 	}
 
 	if instruction > 0xF000 && i2 == 0x33 {
@@ -213,21 +252,27 @@ func (cpu *Cpu) Step() {
 
 		for i, char := range s {
 			i2, _ := strconv.ParseInt(strconv.QuoteRune(char)[1:2], 10, 10)
-			cpu.Memory[cpu.I+uint16(i)] = uint8(i2)
+			cpu.writeMemory(cpu.I+uint16(i), uint8(i2))
 		}
 	}
 
-	if instruction > 0xF00 && i2 == 0x55 {
+	if i1&0xF0 == 0xF0 && i2 == 0x55 {
 		b := i1 - 0xF0
 		for i := 0; uint8(i) <= b; i++ {
-			cpu.Memory[cpu.I+uint16(i)] = cpu.V[i]
+			cpu.writeMemory(cpu.I+uint16(i), cpu.V[i])
+		}
+		if cpu.Quirks.LoadStoreIncrementsI {
+			cpu.I += uint16(b) + 1
 		}
 	}
 
-	if instruction > 0xF00 && i2 == 0x65 {
+	if i1&0xF0 == 0xF0 && i2 == 0x65 {
 		b := i1 - 0xF0
 		for i := 0; uint8(i) <= b; i++ {
-			cpu.V[i] = cpu.Memory[cpu.I+uint16(i)]
+			cpu.V[i] = cpu.readMemory(cpu.I+uint16(i), 1)[0]
+		}
+		if cpu.Quirks.LoadStoreIncrementsI {
+			cpu.I += uint16(b) + 1
 		}
 	}
 }
@@ -335,7 +335,7 @@ func Test_Instruction_Bitwise_SUBN_VF_1(t *testing.T) {
 	cpu.Step()
 
 	testRestRegister(cpu, false, t)
-	testArithmeticsInV0(cpu, before, "Sub", 0x90, t)
+	testArithmeticsInV0(cpu, before, "Subn", 0x70, t)
 	testMemoryUnchanged(cpu, before, t)
 	testProgramStepLength(cpu, before, 2, t)
 }
@@ -383,6 +383,40 @@ func Test_Instruction_Bitwise_SHL_VF_0(t *testing.T) {
 	testProgramStepLength(cpu, before, 2, t)
 }
 
+func Test_Instruction_Bitwise_SUB_Equal(t *testing.T) {
+	cpu := bootstrapTest([]byte{0x80, 0x15})
+	cpu.V[0] = 0x10
+	cpu.V[1] = 0x10
+
+	before := cloneProcessor(cpu)
+	cpu.Step()
+
+	testRestRegister(cpu, true, t)
+	testArithmeticsInV0(cpu, before, "Sub", 0x00, t)
+}
+
+func Test_Instruction_Bitwise_VF_Destination(t *testing.T) {
+	cpu := bootstrapTest([]byte{0x8F, 0x14})
+	cpu.V[0x0F] = 0xFF
+	cpu.V[1] = 0x02
+	cpu.Step()
+
+	testRestRegister(cpu, true, t)
+}
+
+func Test_Instruction_DRW_Collision(t *testing.T) {
+	cpu := bootstrapTest([]byte{0xD0, 0x01, 0xD0, 0x01, 0x00, 0x00})
+	cpu.display = &Framebuffer{}
+	cpu.I = 0x204
+	cpu.Memory[0x204] = 0xFF
+
+	cpu.Step()
+	testRestRegister(cpu, false, t)
+
+	cpu.Step()
+	testRestRegister(cpu, true, t)
+}
+
 func Test_Instruction_SNE_Vx_Vy(t *testing.T) {
 	cpu := bootstrapTest([]byte{0x90, 0x10, 0x90, 0x20})
 	cpu.V[0] = 0x01
@@ -476,15 +510,29 @@ func Test_Instruction_LD_Vx_DT(t *testing.T) {
 func Test_Instruction_LD_Vx_K(t *testing.T) {
 	for i := 0; i < 16; i++ {
 		cpu := bootstrapTest([]byte{0xF0 + uint8(i), 0x0A})
-		cpu.keyboard = &TestKeyboard{HasWaited: false, WaitFor: 0xFF}
+		keyboard := &TestKeyboard{}
+		cpu.keyboard = keyboard
+		cpu.V[i] = 0xFF
+
 		cpu.Step()
+		if cpu.PC != 0x200 || !cpu.WaitingForKey() {
+			t.Errorf("Expected to keep waiting while no key is down, PC is %#04x", cpu.PC)
+		}
 
-		if cpu.keyboard.(*TestKeyboard).HasWaited == false {
-			t.Errorf("We have not waited for keypress")
+		keyboard.KeysDown = []uint8{0x0E}
+		cpu.Step()
+		if cpu.PC != 0x200 || cpu.V[i] != 0xFF {
+			t.Errorf("Expected to keep waiting until the key is released, PC is %#04x", cpu.PC)
 		}
 
-		if cpu.V[i] != 0xFF {
-			t.Errorf("Expected V[%d] to be 0xFF: was: %#02x", i, cpu.V[i])
+		keyboard.KeysDown = nil
+		cpu.Step()
+		if cpu.PC != 0x202 || cpu.WaitingForKey() {
+			t.Errorf("Expected to continue after the key was released, PC is %#04x", cpu.PC)
+		}
+
+		if cpu.V[i] != 0x0E {
+			t.Errorf("Expected V[%d] to be 0x0E: was: %#02x", i, cpu.V[i])
 		}
 	}
 }
@@ -521,8 +569,8 @@ func Test_Instruction_LD_F_Vx(t *testing.T) {
 		cpu.V[i] = uint8(i)
 		cpu.Step()
 
-		if cpu.I != uint16(i*4) {
-			t.Errorf("Expected I to be %#04x: was: %#04x", i*4, cpu.I)
+		if cpu.I != uint16(i*5) {
+			t.Errorf("Expected I to be %#04x: was: %#04x", i*5, cpu.I)
 		}
 	}
 }
@@ -592,6 +640,29 @@ func Test_Instruction_LD_VX_I(t *testing.T) {
 	}
 }
 
+// Every opcode has to run on a fresh cpu, undefined ones such as E155 and
+// 0F55 included. Only RET fails, as the stack is empty.
+func Test_Instruction_Any(t *testing.T) {
+	run := func(op uint16) (err interface{}) {
+		defer func() {
+			err = recover()
+		}()
+		cpu := NewCPU(DefaultMemorySize, &Framebuffer{}, nil)
+		cpu.Memory[0x200], cpu.Memory[0x201] = uint8(op>>8), uint8(op)
+		cpu.Step()
+		return nil
+	}
+
+	for op := 0; op <= 0xFFFF; op++ {
+		if op == 0x00EE {
+			continue
+		}
+		if err := run(uint16(op)); err != nil {
+			t.Errorf("%04X panicked: %v", op, err)
+		}
+	}
+}
+
 func bootstrapTest(code []byte) Cpu {
 	cpu := Cpu{
 		Memory:  make([]uint8, 0x200+len(code)),
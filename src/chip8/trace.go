@@ -0,0 +1,79 @@
+package chip8
+
+// MemoryWrite is a single byte stored to memory by an instruction
+type MemoryWrite struct {
+	Address uint16
+	Value   uint8
+}
+
+// TraceEntry describes the cpu state right after an instruction has been executed
+type TraceEntry struct {
+	Step   uint64
+	PC     uint16
+	Opcode uint16
+	V      [0x10]uint8
+	I      uint16
+	SP     uint16
+	DT     uint8
+	ST     uint8
+	Writes []MemoryWrite
+	// Reads lists the addresses loaded as data, such as sprites and Fx65
+	Reads []uint16
+}
+
+// SetTracer makes the cpu report every executed instruction to tracer.
+// Passing nil disables tracing.
+func (cpu *Cpu) SetTracer(tracer Tracer) {
+	cpu.tracer = tracer
+	cpu.writes = nil
+	cpu.reads = nil
+}
+
+// Tracer returns the tracer set by SetTracer
+func (cpu *Cpu) Tracer() Tracer {
+	return cpu.tracer
+}
+
+func (cpu *Cpu) writeMemory(address uint16, value uint8) {
+	cpu.Memory[address] = value
+	cpu.invalidate(address)
+	if cpu.tracer != nil {
+		cpu.writes = append(cpu.writes, MemoryWrite{Address: address, Value: value})
+	}
+}
+
+func (cpu *Cpu) readMemory(address uint16, n uint16) []uint8 {
+	if cpu.tracer != nil {
+		for i := uint16(0); i < n; i++ {
+			cpu.reads = append(cpu.reads, address+i)
+		}
+	}
+	return cpu.Memory[address : address+n]
+}
+
+func (cpu *Cpu) trace(pc uint16, i1 uint8, i2 uint8) {
+	cpu.traced++
+	cpu.tracer.Trace(TraceEntry{
+		Step:   cpu.traced,
+		PC:     pc,
+		Opcode: uint16(i1)<<8 | uint16(i2),
+		V:      cpu.V,
+		I:      cpu.I,
+		SP:     cpu.SP,
+		DT:     cpu.DT,
+		ST:     cpu.ST,
+		Writes: cpu.writes,
+		Reads:  cpu.reads,
+	})
+	cpu.writes = nil
+	cpu.reads = nil
+}
+
+// MultiTracer forwards every entry to all of its tracers
+type MultiTracer []Tracer
+
+func (m MultiTracer) Trace(entry TraceEntry) {
+	for _, t := range m {
+		t.Trace(entry)
+	}
+}
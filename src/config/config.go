@@ -0,0 +1,155 @@
+// Package config reads settings for chip8 run from a config file. A setting
+// is named after the flag it sets and takes the same values, for example
+//
+//	quirks = cosmac
+//	ipf = 20
+//	palette = amber
+//	keymap = numpad
+//	display = terminal
+//	hotkeys = /home/me/chip8.hotkeys
+//
+// where hotkeys names a hotkey file, or is default or none like -hotkeys.
+// The rom and config flags can not be set from a config file. The buzzer is
+// not emulated, so there is no audio setting.
+package config
+
+import (
+	"bufio"
+	"chip8/src/movie"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Settings maps the names of command line flags to their values
+type Settings map[string]string
+
+// Config holds the settings used for every rom and the overrides for single
+// roms, keyed by their sha256 hash as printed by `chip8 info`
+type Config struct {
+	Defaults Settings
+	Roms     map[string]Settings
+}
+
+// DefaultPath is the config file in the user config directory, for example
+// ~/.config/chip8/config on Linux
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "chip8", "config"), nil
+}
+
+// Load reads the config file at path
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return c, nil
+}
+
+// Read parses a config file. Settings before the first section apply to all
+// roms, a [rom hash] section overrides them for one rom:
+//
+//	# every rom
+//	palette = amber
+//	keymap = numpad
+//
+//	[rom 8b6cc525d64351cda2bc6f223baf237b2af808b825b5f3ccdd90ab8df10b84d1]
+//	# BLINKY
+//	quirks = cosmac
+//	ipf = 20
+func Read(r io.Reader) (*Config, error) {
+	c := &Config{Defaults: Settings{}, Roms: map[string]Settings{}}
+	section := c.Defaults
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") {
+			fields := strings.Fields(strings.TrimSuffix(strings.TrimPrefix(text, "["), "]"))
+			if !strings.HasSuffix(text, "]") || len(fields) != 2 || fields[0] != "rom" || !movie.IsRomHash(fields[1]) {
+				return nil, fmt.Errorf("line %d: expected \"[rom sha256]\", got %q", line, text)
+			}
+			hash := strings.ToLower(fields[1])
+			if _, ok := c.Roms[hash]; !ok {
+				c.Roms[hash] = Settings{}
+			}
+			section = c.Roms[hash]
+			continue
+		}
+
+		i := strings.Index(text, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("line %d: expected \"name = value\", got %q", line, text)
+		}
+		section[strings.TrimSpace(text[:i])] = strings.TrimSpace(text[i+1:])
+	}
+
+	return c, scanner.Err()
+}
+
+// For returns the settings of the rom with the given hash
+func (c *Config) For(romHash string) Settings {
+	s := Settings{}
+	for name, value := range c.Defaults {
+		s[name] = value
+	}
+	for name, value := range c.Roms[strings.ToLower(romHash)] {
+		s[name] = value
+	}
+	return s
+}
+
+// Apply sets every flag in flags that was not given on the command line from
+// s, so that flags win over the settings of a rom, which win over the
+// defaults merged into s by For. Settings without a flag in flags are skipped
+// and returned as unknown, in alphabetical order.
+func Apply(flags *flag.FlagSet, s Settings) (unknown []string, err error) {
+	given := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	for _, name := range s.Names() {
+		if flags.Lookup(name) == nil || name == "rom" || name == "config" {
+			unknown = append(unknown, name)
+			continue
+		}
+		if given[name] {
+			continue
+		}
+		if err := flags.Set(name, s[name]); err != nil {
+			return unknown, fmt.Errorf("%s: %s", name, err)
+		}
+	}
+	return unknown, nil
+}
+
+// Names returns the names of all settings in alphabetical order
+func (s Settings) Names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const hash = "8b6cc525d64351cda2bc6f223baf237b2af808b825b5f3ccdd90ab8df10b84d1"
+
+func TestRead(t *testing.T) {
+	c, err := Read(strings.NewReader(`
+# every rom
+palette = amber
+ipf = 10
+
+[rom ` + strings.ToUpper(hash) + `]
+quirks = cosmac
+ipf = 20
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Settings{"palette": "amber", "ipf": "20", "quirks": "cosmac"}
+	if got := c.For(hash); !reflect.DeepEqual(got, want) {
+		t.Errorf("For(hash) = %v, want %v", got, want)
+	}
+
+	want = Settings{"palette": "amber", "ipf": "10"}
+	if got := c.For(strings.Repeat("0", 64)); !reflect.DeepEqual(got, want) {
+		t.Errorf("For(other) = %v, want %v", got, want)
+	}
+}
+
+func TestRead_Errors(t *testing.T) {
+	for _, text := range []string{
+		"palette",
+		"= amber",
+		"[rom]",
+		"[rom 1234]",
+		"[game " + hash + "]",
+		"[rom " + hash,
+	} {
+		if _, err := Read(strings.NewReader(text)); err == nil {
+			t.Errorf("Read(%q) succeeded", text)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	c, err := Read(strings.NewReader(`
+palette = amber
+ipf = 10
+quirks = default
+hotkeys = none
+volume = 5
+
+[rom ` + hash + `]
+quirks = cosmac
+ipf = 20
+rom = other.ch8
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	rom := flags.String("rom", "", "")
+	ipf := flags.Int("ipf", 5, "")
+	quirks := flags.String("quirks", "", "")
+	palette := flags.String("palette", "", "")
+	hotkeys := flags.String("hotkeys", "default", "")
+	if err := flags.Parse([]string{"-ipf", "30", "-rom", "game.ch8"}); err != nil {
+		t.Fatal(err)
+	}
+
+	unknown, err := Apply(flags, c.For(hash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"rom", "volume"}; !reflect.DeepEqual(unknown, want) {
+		t.Errorf("unknown = %v, want %v", unknown, want)
+	}
+	if *ipf != 30 {
+		t.Errorf("Expected the ipf flag to win over the rom's setting, got %d", *ipf)
+	}
+	if *quirks != "cosmac" {
+		t.Errorf("Expected the rom's quirks to win over the defaults, got %q", *quirks)
+	}
+	if *palette != "amber" || *hotkeys != "none" {
+		t.Errorf("Expected the default palette and hotkeys, got %q and %q", *palette, *hotkeys)
+	}
+	if *rom != "game.ch8" {
+		t.Errorf("Expected the rom not to be set from the config, got %q", *rom)
+	}
+}
+
+func TestApply_InvalidValue(t *testing.T) {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.Int("ipf", 10, "")
+	if _, err := Apply(flags, Settings{"ipf": "fast"}); err == nil {
+		t.Errorf("Expected an error for a value the flag rejects")
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"chip8/src/coverage"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// coverageReport implements `chip8 coverage rom.ch8 run.json...`, which
+// merges the coverage of several runs and exports it.
+func coverageReport(args []string) int {
+	flags := flag.NewFlagSet("coverage", flag.ExitOnError)
+	format := flags.String("format", "listing", "output format: listing, html or json")
+	output := flags.String("o", "", "write to this file instead of stdout")
+	_ = flags.Parse(args)
+
+	if flags.NArg() < 2 {
+		fmt.Fprintln(os.Stderr, "usage: chip8 coverage [-format listing|html|json] [-o file] rom.ch8 run.json...")
+		return 2
+	}
+
+	rom, err := ioutil.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	var merged *coverage.Map
+	for _, path := range flags.Args()[1:] {
+		m, err := readCoverage(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if merged == nil {
+			merged = m
+		} else if err := merged.Merge(m); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			return 2
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		defer f.Close()
+		out = f
+	}
+
+	switch *format {
+	case "listing":
+		merged.WriteListing(out, rom)
+	case "html":
+		merged.WriteHTML(out, flags.Arg(0), rom)
+	case "json":
+		err = merged.WriteJSON(out)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+func readCoverage(path string) (*coverage.Map, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := coverage.ReadJSON(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return m, nil
+}
+
+func writeCoverage(m *coverage.Map, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := m.WriteJSON(f); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return f.Close()
+}
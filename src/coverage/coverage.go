@@ -0,0 +1,186 @@
+package coverage
+
+import (
+	"chip8/src/chip8"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Flags records how a single byte of memory was used
+type Flags uint8
+
+const (
+	Executed Flags = 1 << iota
+	Read
+	Written
+)
+
+func (f Flags) String() string {
+	s := []byte("---")
+	if f&Executed != 0 {
+		s[0] = 'X'
+	}
+	if f&Read != 0 {
+		s[1] = 'R'
+	}
+	if f&Written != 0 {
+		s[2] = 'W'
+	}
+	return string(s)
+}
+
+// Map keeps the usage flags of every memory address and how often it was
+// executed, read or written. It is a chip8.Tracer.
+type Map struct {
+	Flags  []Flags
+	Counts []uint64
+}
+
+func NewMap(memorySize int) *Map {
+	return &Map{Flags: make([]Flags, memorySize), Counts: make([]uint64, memorySize)}
+}
+
+func (m *Map) Trace(e chip8.TraceEntry) {
+	m.mark(e.PC, Executed)
+	m.mark(e.PC+1, Executed)
+	for _, addr := range e.Reads {
+		m.mark(addr, Read)
+	}
+	for _, w := range e.Writes {
+		m.mark(w.Address, Written)
+	}
+}
+
+func (m *Map) mark(addr uint16, f Flags) {
+	if int(addr) < len(m.Flags) {
+		m.Flags[addr] |= f
+		m.Counts[addr]++
+	}
+}
+
+// Merge adds the coverage of other to m
+func (m *Map) Merge(other *Map) error {
+	if len(other.Flags) != len(m.Flags) {
+		return fmt.Errorf("cannot merge coverage of %d bytes into %d bytes", len(other.Flags), len(m.Flags))
+	}
+	for i, f := range other.Flags {
+		m.Flags[i] |= f
+		m.Counts[i] += other.Counts[i]
+	}
+	return nil
+}
+
+// IsCode reports whether addr was ever executed, which is what a
+// disassembler needs to tell code from data.
+func (m *Map) IsCode(addr uint16) bool {
+	return int(addr) < len(m.Flags) && m.Flags[addr]&Executed != 0
+}
+
+// Summary counts how many bytes in [from, to) carry each flag
+type Summary struct {
+	Total     int
+	Executed  int
+	Read      int
+	Written   int
+	Untouched int
+}
+
+func (m *Map) Summarize(from int, to int) Summary {
+	s := Summary{}
+	for i := from; i < to && i < len(m.Flags); i++ {
+		s.Total++
+		f := m.Flags[i]
+		if f&Executed != 0 {
+			s.Executed++
+		}
+		if f&Read != 0 {
+			s.Read++
+		}
+		if f&Written != 0 {
+			s.Written++
+		}
+		if f == 0 {
+			s.Untouched++
+		}
+	}
+	return s
+}
+
+// jsonMap is the on disk format. Each flag list holds [from, to) address
+// ranges, counts holds [address, count] pairs for the addresses in use.
+type jsonMap struct {
+	MemorySize int         `json:"memory_size"`
+	Executed   [][2]uint16 `json:"executed"`
+	Read       [][2]uint16 `json:"read"`
+	Written    [][2]uint16 `json:"written"`
+	Counts     [][2]uint64 `json:"counts"`
+}
+
+func (m *Map) ranges(f Flags) [][2]uint16 {
+	ranges := [][2]uint16{}
+	start := -1
+	for i := 0; i <= len(m.Flags); i++ {
+		set := i < len(m.Flags) && m.Flags[i]&f != 0
+		if set && start < 0 {
+			start = i
+		}
+		if !set && start >= 0 {
+			ranges = append(ranges, [2]uint16{uint16(start), uint16(i)})
+			start = -1
+		}
+	}
+	return ranges
+}
+
+// WriteJSON exports the map as address ranges per flag
+func (m *Map) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	counts := [][2]uint64{}
+	for i, n := range m.Counts {
+		if n > 0 {
+			counts = append(counts, [2]uint64{uint64(i), n})
+		}
+	}
+	return enc.Encode(jsonMap{
+		MemorySize: len(m.Flags),
+		Executed:   m.ranges(Executed),
+		Read:       m.ranges(Read),
+		Written:    m.ranges(Written),
+		Counts:     counts,
+	})
+}
+
+// ReadJSON loads a map written by WriteJSON
+func ReadJSON(r io.Reader) (*Map, error) {
+	var j jsonMap
+	if err := json.NewDecoder(r).Decode(&j); err != nil {
+		return nil, err
+	}
+	if j.MemorySize <= 0 || j.MemorySize > 0x10000 {
+		return nil, fmt.Errorf("invalid memory size %d", j.MemorySize)
+	}
+
+	m := NewMap(j.MemorySize)
+	for _, set := range []struct {
+		ranges [][2]uint16
+		flag   Flags
+	}{{j.Executed, Executed}, {j.Read, Read}, {j.Written, Written}} {
+		for _, r := range set.ranges {
+			if int(r[1]) > len(m.Flags) || r[0] > r[1] {
+				return nil, fmt.Errorf("invalid range %#04x-%#04x", r[0], r[1])
+			}
+			for i := r[0]; i < r[1]; i++ {
+				m.Flags[i] |= set.flag
+			}
+		}
+	}
+	for _, c := range j.Counts {
+		if c[0] >= uint64(len(m.Counts)) {
+			return nil, fmt.Errorf("invalid count address %#04x", c[0])
+		}
+		m.Counts[c[0]] = c[1]
+	}
+	return m, nil
+}
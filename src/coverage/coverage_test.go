@@ -0,0 +1,156 @@
+package coverage
+
+import (
+	"bytes"
+	"chip8/src/chip8"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var rom = []byte{
+	0xA2, 0x08, // 0x200 LD I, 0x208
+	0xD0, 0x12, // 0x202 DRW V0, V1, 2
+	0xF0, 0x33, // 0x204 LD B, V0
+	0x12, 0x06, // 0x206 JP 0x206
+	0xFF, 0x81, // 0x208 sprite
+	0x00, 0x00, // 0x20A BCD digit, 0x20B never touched
+}
+
+func runCoverage() *Map {
+	cpu := chip8.NewCPU(0x300, nil, nil)
+	_ = cpu.LoadProgram(bytes.NewReader(rom))
+
+	m := NewMap(len(cpu.Memory))
+	cpu.SetTracer(m)
+	for i := 0; i < 5; i++ {
+		cpu.Step()
+	}
+	return m
+}
+
+func TestMap_Trace(t *testing.T) {
+	m := runCoverage()
+
+	s := m.Summarize(0x200, 0x200+len(rom))
+	if s.Executed != 8 || s.Read != 2 || s.Written != 3 || s.Untouched != 1 {
+		t.Errorf("Unexpected summary %+v", s)
+	}
+
+	if m.Flags[0x208] != Read|Written {
+		t.Errorf("Expected 0x208 to be read and written, got %s", m.Flags[0x208])
+	}
+
+	if !m.IsCode(0x206) || m.IsCode(0x209) {
+		t.Errorf("Expected 0x206 to be code and 0x209 to be data")
+	}
+
+	// The jump ran twice, the sprite row was drawn and then overwritten
+	if m.Counts[0x200] != 1 || m.Counts[0x206] != 2 || m.Counts[0x208] != 2 || m.Counts[0x20B] != 0 {
+		t.Errorf("Unexpected counts % d", m.Counts[0x200:0x20C])
+	}
+}
+
+func TestMap_JSONRoundTrip(t *testing.T) {
+	m := runCoverage()
+
+	out := bytes.Buffer{}
+	if err := m.WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := ReadJSON(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(flagBytes(loaded), flagBytes(m)) || !reflect.DeepEqual(loaded.Counts, m.Counts) {
+		t.Errorf("Coverage changed after a JSON round trip")
+	}
+}
+
+func TestReadJSON_Errors(t *testing.T) {
+	for _, text := range []string{
+		`{"memory_size": -1}`,
+		`{"memory_size": 0}`,
+		`{"memory_size": 65537}`,
+		`{"memory_size": 16, "executed": [[2, 17]]}`,
+		`{"memory_size": 16, "counts": [[16, 1]]}`,
+	} {
+		if _, err := ReadJSON(strings.NewReader(text)); err == nil {
+			t.Errorf("Expected %s to be rejected", text)
+		}
+	}
+}
+
+func TestMap_Merge(t *testing.T) {
+	a := NewMap(0x300)
+	b := NewMap(0x300)
+	a.Flags[0x200] = Executed
+	b.Flags[0x200] = Read
+	b.Flags[0x201] = Written
+	a.Counts[0x200], b.Counts[0x200] = 3, 4
+
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Flags[0x200] != Executed|Read || a.Flags[0x201] != Written {
+		t.Errorf("Unexpected merge result %s %s", a.Flags[0x200], a.Flags[0x201])
+	}
+	if a.Counts[0x200] != 7 {
+		t.Errorf("Expected merged counts to add up to 7, got %d", a.Counts[0x200])
+	}
+
+	if err := a.Merge(NewMap(0x10)); err == nil {
+		t.Errorf("Expected merging maps of different sizes to fail")
+	}
+}
+
+func TestMap_WriteListing(t *testing.T) {
+	out := bytes.Buffer{}
+	runCoverage().WriteListing(&out, rom)
+
+	if !strings.Contains(out.String(), "0x0202  d012  X--  DRW") {
+		t.Errorf("Expected DRW to be listed as executed code:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "0x020b  00    ---") {
+		t.Errorf("Expected 0x20b to be listed as untouched data:\n%s", out.String())
+	}
+}
+
+func TestMap_WriteHTML(t *testing.T) {
+	out := bytes.Buffer{}
+	runCoverage().WriteHTML(&out, "test", rom)
+
+	// The most used address gets the full colour, others are lighter
+	for _, cell := range []string{
+		`<td style="background:#4caf50" title="0x0206 X-- 2">12</td>`,
+		`<td style="background:` + shade(colors[Executed], 1, 2) + `" title="0x0200 X-- 1">a2</td>`,
+		`<td style="background:#eeeeee" title="0x020b --- 0">00</td>`,
+	} {
+		if !strings.Contains(out.String(), cell) {
+			t.Errorf("Expected %s in:\n%s", cell, out.String())
+		}
+	}
+}
+
+func TestShade(t *testing.T) {
+	if c := shade("#4caf50", 100, 100); c != "#4caf50" {
+		t.Errorf("Expected the most used address to get the full colour, got %s", c)
+	}
+	if c := shade("#000000", 0, 100); c != "#bfbfbf" {
+		t.Errorf("Expected an unused address to get a quarter of the colour, got %s", c)
+	}
+	if once, often := shade("#000000", 1, 100), shade("#000000", 10, 100); once <= often {
+		t.Errorf("Expected %s for 1 use to be lighter than %s for 10", once, often)
+	}
+}
+
+func flagBytes(m *Map) []byte {
+	b := make([]byte, len(m.Flags))
+	for i, f := range m.Flags {
+		b[i] = byte(f)
+	}
+	return b
+}
@@ -0,0 +1,112 @@
+package coverage
+
+import (
+	"chip8/src/chip8"
+	"fmt"
+	"html"
+	"io"
+	"math"
+)
+
+// WriteListing writes an annotated listing of rom as loaded at 0x200.
+// Executed words are shown as instructions, everything else as data bytes.
+func (m *Map) WriteListing(w io.Writer, rom []byte) {
+	s := m.Summarize(0x200, 0x200+len(rom))
+	fmt.Fprintf(w, "; %d bytes, %d executed, %d read, %d written, %d untouched\n",
+		s.Total, s.Executed, s.Read, s.Written, s.Untouched)
+
+	for i := 0; i < len(rom); {
+		addr := uint16(0x200 + i)
+		if m.IsCode(addr) && i+1 < len(rom) {
+			op := uint16(rom[i])<<8 | uint16(rom[i+1])
+			fmt.Fprintf(w, "%#04x  %04x  %s  %s\n", addr, op, m.flags(addr), chip8.OpcodeClass(op))
+			i += 2
+			continue
+		}
+
+		fmt.Fprintf(w, "%#04x  %02x    %s  %08b\n", addr, rom[i], m.flags(addr), rom[i])
+		i++
+	}
+}
+
+func (m *Map) flags(addr uint16) Flags {
+	if int(addr) < len(m.Flags) {
+		return m.Flags[addr]
+	}
+	return 0
+}
+
+func (m *Map) count(addr uint16) uint64 {
+	if int(addr) < len(m.Counts) {
+		return m.Counts[addr]
+	}
+	return 0
+}
+
+var colors = map[Flags]string{
+	0:                         "#eeeeee",
+	Executed:                  "#4caf50",
+	Read:                      "#2196f3",
+	Written:                   "#f44336",
+	Executed | Read:           "#00bcd4",
+	Executed | Written:        "#ff9800",
+	Read | Written:            "#9c27b0",
+	Executed | Read | Written: "#795548",
+}
+
+// shade returns color mixed with white so that addresses used count times
+// out of the most used max look lighter the less they were used. The scale
+// is logarithmic, or a loop would leave everything else white.
+func shade(color string, count uint64, max uint64) string {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(color, "#%02x%02x%02x", &r, &g, &b); err != nil || max == 0 {
+		return color
+	}
+	strength := 0.25 + 0.75*math.Log1p(float64(count))/math.Log1p(float64(max))
+	mix := func(c uint8) uint8 {
+		return uint8(math.Round(0xFF - (0xFF-float64(c))*strength))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", mix(r), mix(g), mix(b))
+}
+
+// WriteHTML writes a heatmap of rom with 16 bytes per row. The colour of a
+// byte shows how it was used and gets darker the more often it was.
+func (m *Map) WriteHTML(w io.Writer, title string, rom []byte) {
+	s := m.Summarize(0x200, 0x200+len(rom))
+	var max uint64
+	for i := range rom {
+		if n := m.count(uint16(0x200 + i)); n > max {
+			max = n
+		}
+	}
+
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>%s</title>\n", html.EscapeString(title))
+	fmt.Fprintln(w, "<style>body{font-family:monospace}td{width:2.2em;text-align:center}th{text-align:right;padding-right:1em}</style>")
+	fmt.Fprintln(w, "</head><body>")
+	fmt.Fprintf(w, "<h1>%s</h1>\n", html.EscapeString(title))
+	fmt.Fprintf(w, "<p>%d bytes, %d executed, %d read, %d written, %d untouched</p>\n",
+		s.Total, s.Executed, s.Read, s.Written, s.Untouched)
+
+	fmt.Fprintln(w, "<p>")
+	for _, f := range []Flags{Executed, Read, Written, 0} {
+		fmt.Fprintf(w, "<span style=\"background:%s\">&nbsp;%s&nbsp;</span> ", colors[f], f)
+	}
+	fmt.Fprintf(w, "used once <span style=\"background:%s\">&nbsp;&nbsp;</span> to %d times <span style=\"background:%s\">&nbsp;&nbsp;</span>\n",
+		shade(colors[Executed], 1, max), max, colors[Executed])
+	fmt.Fprintln(w, "</p>\n<table>")
+
+	for row := 0; row < len(rom); row += 16 {
+		fmt.Fprintf(w, "<tr><th>%#04x</th>", 0x200+row)
+		for i := row; i < row+16 && i < len(rom); i++ {
+			addr := uint16(0x200 + i)
+			f, n := m.flags(addr), m.count(addr)
+			color := colors[f]
+			if f != 0 {
+				color = shade(color, n, max)
+			}
+			fmt.Fprintf(w, "<td style=\"background:%s\" title=\"%#04x %s %d\">%02x</td>", color, addr, f, n, rom[i])
+		}
+		fmt.Fprintln(w, "</tr>")
+	}
+	fmt.Fprintln(w, "</table></body></html>")
+}
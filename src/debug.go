@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"chip8/src/chip8"
+	"chip8/src/debugger"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runDebug implements `chip8 debug [flags] rom.ch8`, which runs the rom
+// headless under a line based debugger reading commands from stdin.
+func runDebug(args []string) int {
+	flags := flag.NewFlagSet("debug", flag.ExitOnError)
+	romFlag := flags.String("rom", "", "rom to debug, instead of the positional argument")
+	quirksName := flags.String("quirks", "default", "quirk profile ("+strings.Join(chip8.QuirkProfileNames(), ", ")+")")
+	memorySize := flags.Int("memory", chip8.DefaultMemorySize, "memory size in bytes")
+	seed := flags.Uint64("seed", 1, "random number generator seed")
+	ipf := flags.Int("ipf", 10, "instructions per frame")
+	configPath := flags.String("config", "", "config file, defaults to "+defaultConfigPath()+", none to ignore it")
+	_ = flags.Parse(args)
+
+	_, rom, err := readRom(flags, *romFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := applyConfig(flags, *configPath, rom, false); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *memorySize <= 0x200 || *memorySize > 0x7FFF {
+		fmt.Fprintf(os.Stderr, "memory size must be more than %d and at most %d bytes\n", 0x200, 0x7FFF)
+		return 2
+	}
+	quirks, ok := chip8.QuirkProfile(*quirksName)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown quirk profile %q\n", *quirksName)
+		return 2
+	}
+
+	d := debugger.New(int16(*memorySize))
+	d.Runner.InstructionsPerFrame = *ipf
+	d.Runner.Cpu.Quirks = quirks
+	d.Runner.Cpu.SetRng(chip8.NewSeededRng(*seed))
+	if err := d.Runner.Cpu.LoadProgram(bytes.NewReader(rom)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if err := d.Run(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,334 @@
+package debugger
+
+import (
+	"bufio"
+	"chip8/src/chip8"
+	"chip8/src/headless"
+	"chip8/src/statedumpers"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxContinueFrames limits how long continue runs without reaching a breakpoint
+const MaxContinueFrames = 60 * 60
+
+// Keys is the keyboard of the debugger, keys stay down until they are changed
+type Keys struct {
+	Down [16]bool
+}
+
+func (k *Keys) IsDown(key uint8) bool {
+	return k.Down[key&0xF]
+}
+
+func (k *Keys) SetFrame(_ uint64) {
+}
+
+// Debugger is a line based debugger for a headless machine
+type Debugger struct {
+	Runner      *headless.Runner
+	Keys        *Keys
+	breakpoints map[uint16]bool
+	out         io.Writer
+	last        string
+}
+
+// New creates a debugger around a headless machine with memorySize bytes of memory
+func New(memorySize int16) *Debugger {
+	keys := &Keys{}
+	return &Debugger{
+		Runner:      headless.NewRunnerWithKeyboard(memorySize, keys),
+		Keys:        keys,
+		breakpoints: map[uint16]bool{},
+	}
+}
+
+// command runs a debugger command with its arguments
+type command struct {
+	name  string
+	usage string
+	run   func(d *Debugger, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"step", "step [n]          run n instructions, stopping at breakpoints", (*Debugger).step},
+		{"frame", "frame [n]         run until n more frames have finished", (*Debugger).frame},
+		{"continue", "continue [frames] run until a breakpoint is reached", (*Debugger).cont},
+		{"break", "break [addr]      toggle a breakpoint or list them", (*Debugger).toggleBreakpoint},
+		{"regs", "regs              show the registers", (*Debugger).regs},
+		{"mem", "mem addr [n]      dump n bytes of memory", (*Debugger).mem},
+		{"dis", "dis [addr] [n]    disassemble n instructions", (*Debugger).dis},
+		{"screen", "screen            show the screen", (*Debugger).screen},
+		{"keys", "keys [keys]       hold exactly the given hex keys down, e.g. keys 5a", (*Debugger).keys},
+		{"help", "help              list the commands", (*Debugger).help},
+	}
+}
+
+// Run reads commands from in until quit or the end of the input. An empty
+// line repeats the previous command. Commands can be abbreviated.
+func (d *Debugger) Run(in io.Reader, out io.Writer) error {
+	d.out = out
+	scanner := bufio.NewScanner(in)
+	d.showNext()
+	for {
+		fmt.Fprint(out, "(chip8) ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			line = d.last
+		}
+		d.last = line
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if strings.HasPrefix("quit", fields[0]) {
+			return nil
+		}
+
+		c, ok := lookup(fields[0])
+		if !ok {
+			fmt.Fprintf(out, "unknown command %q, try help\n", fields[0])
+			continue
+		}
+		if err := c.run(d, fields[1:]); err != nil {
+			fmt.Fprintln(out, err)
+		}
+	}
+}
+
+// lookup finds the first command that starts with name
+func lookup(name string) (command, bool) {
+	for _, c := range commands {
+		if strings.HasPrefix(c.name, name) {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+func (d *Debugger) step(args []string) error {
+	n, err := count(args, 0, 1)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		if !d.Runner.Step() {
+			return d.leftMemory()
+		}
+		if i+1 < n && d.breakpoints[d.Runner.Cpu.PC] {
+			fmt.Fprintf(d.out, "breakpoint at %#03x\n", d.Runner.Cpu.PC)
+			break
+		}
+	}
+	d.showNext()
+	return nil
+}
+
+func (d *Debugger) frame(args []string) error {
+	n, err := count(args, 0, 1)
+	if err != nil {
+		return err
+	}
+
+	if !d.Runner.RunFrames(uint64(n)) {
+		return d.leftMemory()
+	}
+	d.showNext()
+	return nil
+}
+
+func (d *Debugger) cont(args []string) error {
+	frames, err := count(args, 0, MaxContinueFrames)
+	if err != nil {
+		return err
+	}
+
+	end := d.Runner.Frame + uint64(frames)
+	for d.Runner.Frame < end {
+		if !d.Runner.Step() {
+			return d.leftMemory()
+		}
+		if d.breakpoints[d.Runner.Cpu.PC] {
+			fmt.Fprintf(d.out, "breakpoint at %#03x\n", d.Runner.Cpu.PC)
+			d.showNext()
+			return nil
+		}
+	}
+
+	fmt.Fprintf(d.out, "no breakpoint reached within %d frames\n", frames)
+	d.showNext()
+	return nil
+}
+
+func (d *Debugger) toggleBreakpoint(args []string) error {
+	if len(args) == 0 {
+		var addresses []int
+		for addr := range d.breakpoints {
+			addresses = append(addresses, int(addr))
+		}
+		sort.Ints(addresses)
+		for _, addr := range addresses {
+			fmt.Fprintf(d.out, "%#03x\n", addr)
+		}
+		return nil
+	}
+
+	addr, err := address(args[0])
+	if err != nil {
+		return err
+	}
+	if d.breakpoints[addr] {
+		delete(d.breakpoints, addr)
+		fmt.Fprintf(d.out, "removed breakpoint at %#03x\n", addr)
+	} else {
+		d.breakpoints[addr] = true
+		fmt.Fprintf(d.out, "set breakpoint at %#03x\n", addr)
+	}
+	return nil
+}
+
+func (d *Debugger) regs(_ []string) error {
+	statedumpers.TableDumper{To: d.out}.DumpState(*d.Runner.Cpu)
+	fmt.Fprintf(d.out, "frame %d\n", d.Runner.Frame)
+	return nil
+}
+
+func (d *Debugger) mem(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: mem addr [n]")
+	}
+	addr, err := address(args[0])
+	if err != nil {
+		return err
+	}
+	n, err := count(args, 1, 16)
+	if err != nil {
+		return err
+	}
+
+	memory := d.Runner.Cpu.Memory
+	for row := int(addr); row < int(addr)+n && row < len(memory); row += 16 {
+		fmt.Fprintf(d.out, "%#03x ", row)
+		for i := row; i < row+16 && i < int(addr)+n && i < len(memory); i++ {
+			fmt.Fprintf(d.out, " %02x", memory[i])
+		}
+		fmt.Fprintln(d.out)
+	}
+	return nil
+}
+
+func (d *Debugger) dis(args []string) error {
+	addr := d.Runner.Cpu.PC
+	if len(args) > 0 {
+		var err error
+		if addr, err = address(args[0]); err != nil {
+			return err
+		}
+	}
+	n, err := count(args, 1, 10)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		d.showInstruction(addr + uint16(i*2))
+	}
+	return nil
+}
+
+func (d *Debugger) screen(_ []string) error {
+	for y := 0; y < chip8.ScreenHeight; y++ {
+		row := make([]byte, chip8.ScreenWidth)
+		for x := range row {
+			row[x] = '.'
+			if d.Runner.Display.GetPixel(uint8(x), uint8(y)) {
+				row[x] = '#'
+			}
+		}
+		fmt.Fprintln(d.out, string(row))
+	}
+	return nil
+}
+
+func (d *Debugger) keys(args []string) error {
+	var down [16]bool
+	for _, arg := range args {
+		for _, c := range arg {
+			key, err := strconv.ParseUint(string(c), 16, 4)
+			if err != nil {
+				return fmt.Errorf("invalid key %q", c)
+			}
+			down[key] = true
+		}
+	}
+	d.Keys.Down = down
+	return nil
+}
+
+func (d *Debugger) help(_ []string) error {
+	for _, c := range commands {
+		fmt.Fprintln(d.out, c.usage)
+	}
+	fmt.Fprintln(d.out, "quit              leave the debugger")
+	return nil
+}
+
+func (d *Debugger) leftMemory() error {
+	return fmt.Errorf("program counter left memory at %#03x", d.Runner.Cpu.PC)
+}
+
+// showNext prints the instruction that runs next
+func (d *Debugger) showNext() {
+	d.showInstruction(d.Runner.Cpu.PC)
+}
+
+func (d *Debugger) showInstruction(addr uint16) {
+	memory := d.Runner.Cpu.Memory
+	if int(addr)+1 >= len(memory) {
+		return
+	}
+
+	marker := ' '
+	if addr == d.Runner.Cpu.PC {
+		marker = '>'
+	}
+	if d.breakpoints[addr] {
+		marker = '*'
+	}
+	opcode := uint16(memory[addr])<<8 | uint16(memory[addr+1])
+	fmt.Fprintf(d.out, "%c %#03x  %04x  %s\n", marker, addr, opcode, chip8.Disassemble(opcode))
+}
+
+// count parses the optional positive argument i, falling back to def
+func count(args []string, i int, def int) (int, error) {
+	if len(args) <= i {
+		return def, nil
+	}
+	n, err := strconv.Atoi(args[i])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid count %q", args[i])
+	}
+	return n, nil
+}
+
+// address parses a hexadecimal address with or without 0x
+func address(arg string) (uint16, error) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(arg), "0x"), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", arg)
+	}
+	return uint16(v), nil
+}
@@ -0,0 +1,84 @@
+package debugger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var program = []byte{
+	0x60, 0x05, // 0x200 LD V0, 5
+	0x70, 0x01, // 0x202 ADD V0, 1
+	0xE1, 0x9E, // 0x204 SKP V1
+	0x12, 0x02, // 0x206 JP 0x202
+	0xF0, 0x29, // 0x208 LD F, V0
+	0xD0, 0x05, // 0x20A DRW V0, V0, 5
+	0x12, 0x0C, // 0x20C JP 0x20C
+}
+
+func run(t *testing.T, script string) (*Debugger, string) {
+	t.Helper()
+	d := New(0x1000)
+	if err := d.Runner.Cpu.LoadProgram(bytes.NewReader(program)); err != nil {
+		t.Fatal(err)
+	}
+
+	out := bytes.Buffer{}
+	if err := d.Run(strings.NewReader(script), &out); err != nil {
+		t.Fatal(err)
+	}
+	return d, out.String()
+}
+
+func TestDebugger_Step(t *testing.T) {
+	d, out := run(t, "s\n\nstep 2\n")
+
+	// The empty line repeats the step, and the loop jumps back without key 0
+	if d.Runner.Cpu.PC != 0x202 || d.Runner.Cpu.V[0] != 6 {
+		t.Errorf("Expected 4 instructions to run, PC is %#x", d.Runner.Cpu.PC)
+	}
+	for _, expected := range []string{
+		"> 0x200  6005  LD V0, 0x05\n",
+		"> 0x202  7001  ADD V0, 0x01\n",
+		"> 0x204  e19e  SKP V1\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in output:\n%s", expected, out)
+		}
+	}
+}
+
+func TestDebugger_Breakpoints(t *testing.T) {
+	d, out := run(t, "b 208\nc 5\nkeys 0\nc\nregs\nq\nstep\n")
+
+	if !strings.Contains(out, "set breakpoint at 0x208\n") || !strings.Contains(out, "no breakpoint reached within 5 frames") {
+		t.Errorf("Expected continue to give up while key 0 is up:\n%s", out)
+	}
+	if !strings.Contains(out, "breakpoint at 0x208\n* 0x208  f029  LD F, V0") {
+		t.Errorf("Expected to stop at the breakpoint once key 0 is down:\n%s", out)
+	}
+	if d.Runner.Cpu.PC != 0x208 {
+		t.Errorf("Expected commands after quit to be ignored, PC is %#x", d.Runner.Cpu.PC)
+	}
+	if !strings.Contains(out, "V0") || !strings.Contains(out, "frame ") {
+		t.Errorf("Expected the registers to be shown:\n%s", out)
+	}
+}
+
+func TestDebugger_Inspect(t *testing.T) {
+	_, out := run(t, "mem 200 4\ndis 20a 2\nframe 2\nscreen\nnope\nmem zz\n")
+
+	for _, expected := range []string{
+		"0x200  60 05 70 01\n",
+		"  0x20a  d005  DRW V0, V0, 5\n  0x20c  120c  JP 0x20c\n",
+		"unknown command \"nope\", try help\n",
+		"invalid address \"zz\"\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in output:\n%s", expected, out)
+		}
+	}
+	if strings.Count(out, strings.Repeat(".", 64)+"\n") != 32 {
+		t.Errorf("Expected an empty 32 line screen:\n%s", out)
+	}
+}
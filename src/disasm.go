@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"chip8/src/asm"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// disassemble implements `chip8 disasm [-o file] [-coverage map.json] rom.ch8`.
+// The listing can be assembled again with `chip8 asm`.
+func disassemble(args []string) int {
+	flags := flag.NewFlagSet("disasm", flag.ExitOnError)
+	romFlag := flags.String("rom", "", "rom to disassemble, instead of the positional argument")
+	output := flags.String("o", "", "write to this file instead of stdout")
+	coverageIn := flags.String("coverage", "", "coverage map from chip8 run -coverage, bytes it never saw executed are listed as data")
+	_ = flags.Parse(args)
+
+	_, rom, err := readRom(flags, *romFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	var isCode func(address uint16) bool
+	if *coverageIn != "" {
+		m, err := readCoverage(*coverageIn)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		isCode = m.IsCode
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := asm.WriteCoverageListing(out, rom, isCode); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// assemble implements `chip8 asm [-o rom.ch8] source.asm`. The rom is written
+// next to the source unless -o is given, reading - means stdin.
+func assemble(args []string) int {
+	flags := flag.NewFlagSet("asm", flag.ExitOnError)
+	output := flags.String("o", "", "rom file to write, defaults to the source path with a .ch8 extension")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: chip8 asm [-o rom.ch8] source.asm")
+		return 2
+	}
+	path := flags.Arg(0)
+
+	var source []byte
+	var err error
+	if path == "-" {
+		source, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		source, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	rom, err := asm.Assemble(bytes.NewReader(source))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+		return 2
+	}
+
+	if *output == "" {
+		if path == "-" {
+			fmt.Fprintln(os.Stderr, "-o is required when reading from stdin")
+			return 2
+		}
+		*output = strings.TrimSuffix(path, filepath.Ext(path)) + ".ch8"
+	}
+	if err := ioutil.WriteFile(*output, rom, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
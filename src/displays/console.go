@@ -1,90 +1,256 @@
 package displays
 
 import (
+	"chip8/src/chip8"
+	"chip8/src/input"
+	"chip8/src/terminal"
+	"chip8/src/video"
+	"fmt"
 	"github.com/gdamore/tcell"
+	"strings"
+	"time"
 )
 
-var bits = []byte{0x80, 0x40, 0x20, 0x10}
+var bits = []byte{0x80, 0x40, 0x20, 0x10, 0x08, 0x04, 0x02, 0x01}
 
+// TextDisplay draws the screen in a terminal with tcell and reads the keypad from it
 type TextDisplay struct {
-	screen tcell.Screen
-	tCell  tcell.Style
-	eCell  tcell.Style
-	Memory [2048]bool
+	chip8.Framebuffer
+
+	screen   tcell.Screen
+	events   chan tcell.Event
+	encoder  terminal.Encoder
+	keyboard *terminal.Keyboard
+	hotkeys  *terminalHotkeys
+
+	palette     video.Palette
+	persistence *video.Persistence
+	quit        bool
+	drawn       string
+
+	notice      string
+	noticeUntil time.Time
+	status      string
+}
+
+// SetPalette changes the colours of the screen
+func (t *TextDisplay) SetPalette(p video.Palette) {
+	t.palette = p
+}
+
+// SetPersistence enables the phosphor effect, nil turns it off
+func (t *TextDisplay) SetPersistence(p *video.Persistence) {
+	t.persistence = p
+}
+
+// SetEncoder changes the characters the screen is drawn with. The zero
+// Encoder picks one that fits the terminal.
+func (t *TextDisplay) SetEncoder(e terminal.Encoder) {
+	t.encoder = e
+}
+
+// SetKeymap replaces the key bindings. Only single character key names can
+// be typed in a terminal, the others are ignored.
+func (t *TextDisplay) SetKeymap(keymap input.Keymap) error {
+	keyboard, err := terminal.NewKeyboard(keymap)
+	if err != nil {
+		return err
+	}
+	t.keyboard = keyboard
+	return nil
+}
+
+// SetHotkeys binds the hotkeys a terminal can report, see parseTerminalKey.
+// Every press and release of a hotkey is passed to handle.
+func (t *TextDisplay) SetHotkeys(hotkeys input.Hotkeys, handle func(a input.Action, down bool)) {
+	keys := map[terminalKey]input.Action{}
+	for action, name := range hotkeys {
+		if k, ok := parseTerminalKey(name); ok {
+			keys[k] = action
+		}
+	}
+
+	t.hotkeys = &terminalHotkeys{keys: keys, releases: map[input.Action]time.Time{}, handle: handle}
 }
 
-func (t *TextDisplay) Clear() {
-	t.screen.Clear()
+// Notify shows text in the bottom row of the terminal for NoticeDuration
+func (t *TextDisplay) Notify(text string) {
+	t.notice = text
+	t.noticeUntil = time.Now().Add(NoticeDuration)
+}
+
+// SetStatus shows lines of text next to each other in the top row of the
+// terminal until it is called with no lines
+func (t *TextDisplay) SetStatus(lines []string) {
+	t.status = strings.Join(lines, "  ")
 }
 
 func (t *TextDisplay) Dispose() {
 	t.screen.Fini()
 }
-func (t *TextDisplay) SetSprite(x uint8, y uint8, sprites []uint8) bool {
-	c := false
-	for i, sprite := range sprites {
-		for i2, bit := range bits {
-			if t.SetPixel(x+uint8(i2), y+uint8(i), sprite&bit == bit) {
-				c = true
+
+// PumpEvents handles the terminal events that arrived since the last call.
+// Escape and Ctrl+C request to quit.
+func (t *TextDisplay) PumpEvents() {
+	for {
+		select {
+		case e := <-t.events:
+			t.handleEvent(e)
+		default:
+			if t.hotkeys != nil {
+				t.hotkeys.releaseExpired()
 			}
+			return
 		}
 	}
-	return c
 }
 
-func (t *TextDisplay) GetPixel(x uint8, y uint8) bool {
-	return t.Memory[int32(x)+int32(y)*64]
+func (t *TextDisplay) handleEvent(e tcell.Event) {
+	switch e := e.(type) {
+	case *tcell.EventKey:
+		switch {
+		case e.Key() == tcell.KeyEscape || e.Key() == tcell.KeyCtrlC:
+			t.quit = true
+		case t.hotkeys != nil && t.hotkeys.press(eventKey(e)):
+		case e.Key() == tcell.KeyRune:
+			t.keyboard.Press(e.Rune())
+		}
+	case *tcell.EventResize:
+		t.screen.Clear()
+		t.screen.Sync()
+	}
 }
 
-func (t *TextDisplay) SetPixel(x uint8, y uint8, on bool) bool {
-	c := false
+// QuitRequested reports whether Escape or Ctrl+C was pressed
+func (t *TextDisplay) QuitRequested() bool {
+	return t.quit
+}
 
-	if on && t.Memory[x+(y*64)] {
-		c = true
-	}
-	x2 := int32(x) % 64
-	t.Memory[x2+int32(y)*64] = on
+// Paused is always false, terminals do not report losing focus
+func (t *TextDisplay) Paused() bool {
+	return false
+}
+
+func (t *TextDisplay) IsDown(key uint8) bool {
+	return t.keyboard.IsDown(key)
+}
 
-	if t.GetPixel(x, y) {
-		t.screen.SetCell(int(x2), int(y), t.tCell, ' ')
+// Render draws the screen centred in the terminal, it is meant to be called once per frame
+func (t *TextDisplay) Render() {
+	var levels []uint8
+	if t.persistence != nil {
+		levels = t.persistence.Update(t.Pixels[:])
 	} else {
-		t.screen.SetCell(int(x2), int(y), t.eCell, ' ')
+		levels = video.Levels(t.Pixels[:])
+	}
+
+	width, height := t.screen.Size()
+	encoder := t.encoder
+	if encoder.Name == "" {
+		encoder = terminal.Fit(width, height, chip8.ScreenWidth, chip8.ScreenHeight)
+	}
+	if encoder.Name != t.drawn {
+		// Different encoders cover different areas, clear what is left of the last one
+		t.screen.Clear()
+		t.drawn = encoder.Name
+	}
+
+	cols, rows := encoder.Size(chip8.ScreenWidth, chip8.ScreenHeight)
+	if width < cols || height < rows {
+		t.screen.Clear()
+		t.drawText(0, 0, fmt.Sprintf("Terminal too small, %dx%d needed", cols, rows))
+		t.screen.Show()
+		return
+	}
+
+	left, top := (width-cols)/2, (height-rows)/2
+	for i, cell := range encoder.Encode(levels, chip8.ScreenWidth, chip8.ScreenHeight) {
+		style := tcell.StyleDefault.Foreground(t.colour(cell.Foreground)).Background(t.colour(cell.Background))
+		t.screen.SetContent(left+i%cols, top+i/cols, cell.Rune, nil, style)
 	}
+	// Outside the screen the rows have to be cleared, on the screen they were just drawn
+	t.drawStatus(top > 0)
+	t.drawNotice(top+rows < height)
+	t.screen.Show()
+}
 
-	return c
+func (t *TextDisplay) colour(level uint8) tcell.Color {
+	c := t.palette.Blend(level)
+	return tcell.NewRGBColor(int32(c.R), int32(c.G), int32(c.B))
 }
-func (t *TextDisplay) Render() {
-	t.screen.Clear()
-	for y := uint8(0); y < 32; y++ {
-		for x := uint8(0); x < 64; x++ {
 
-		}
+// drawNotice writes the notification into the bottom row, clearing the rest
+// of the row first if clear is set
+func (t *TextDisplay) drawNotice(clear bool) {
+	_, height := t.screen.Size()
+	if clear {
+		t.clearRow(height - 1)
 	}
+	t.drawText(0, height-1, t.currentNotice())
+}
 
-	t.screen.Sync()
+// drawStatus writes the status line into the top row, clearing the rest of
+// the row first if clear is set
+func (t *TextDisplay) drawStatus(clear bool) {
+	if clear {
+		t.clearRow(0)
+	}
+	t.drawText(0, 0, t.status)
 }
 
-// NewTextDisplay renders the screen using the library tcell.
-// Still needs work
+func (t *TextDisplay) clearRow(y int) {
+	width, _ := t.screen.Size()
+	for x := 0; x < width; x++ {
+		t.screen.SetContent(x, y, ' ', nil, tcell.StyleDefault)
+	}
+}
+
+// currentNotice returns the notification or "" once it has expired
+func (t *TextDisplay) currentNotice() string {
+	if time.Now().Before(t.noticeUntil) {
+		return t.notice
+	}
+	return ""
+}
+
+func (t *TextDisplay) drawText(x int, y int, text string) {
+	for i, r := range []rune(text) {
+		t.screen.SetContent(x+i, y, r, nil, tcell.StyleDefault)
+	}
+}
+
+// NewTextDisplay renders the screen using the library tcell
 func NewTextDisplay() (*TextDisplay, error) {
 	screen, err := tcell.NewScreen()
 	if err != nil {
-		return &TextDisplay{}, err
+		return nil, err
 	}
 	err = screen.Init()
 	if err != nil {
-		return &TextDisplay{}, err
-
+		return nil, err
 	}
 
-	filledState := tcell.StyleDefault.Background(tcell.ColorWhite).Foreground(tcell.ColorWhite)
-	emptyState := tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorBlack)
+	screen.HideCursor()
+	screen.SetStyle(tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorBlack))
+
+	t := &TextDisplay{
+		screen:  screen,
+		events:  make(chan tcell.Event, 64),
+		palette: video.Palettes[video.DefaultPalette],
+	}
+	keymap, _ := input.Preset(input.DefaultPreset)
+	if err := t.SetKeymap(keymap); err != nil {
+		screen.Fini()
+		return nil, err
+	}
 
-	screen.SetStyle(emptyState)
+	// PollEvent blocks, so events are read in the background and handed to
+	// the emulator loop by PumpEvents. It returns nil once the screen is closed.
+	go func() {
+		for e := screen.PollEvent(); e != nil; e = screen.PollEvent() {
+			t.events <- e
+		}
+	}()
 
-	return &TextDisplay{
-		screen: screen,
-		tCell:  filledState,
-		eCell:  emptyState,
-	}, nil
+	return t, nil
 }
@@ -0,0 +1,104 @@
+package displays
+
+import (
+	"chip8/src/input"
+	"fmt"
+	"github.com/veandco/go-sdl2/sdl"
+	"strings"
+)
+
+// axisDeadzone is how far a stick has to move before it counts as a key press
+const axisDeadzone = 16000
+
+// Gamepads maps SDL game controllers onto the CHIP-8 keypad. Controllers can
+// be plugged in and out at any time. It is an EventHandler, so register it
+// with NewSDLDisplay.AddEventHandler.
+//
+// Only devices SDL has a game controller mapping for are used. Plain
+// joysticks without one are ignored, a mapping for them can be added with
+// the SDL_GAMECONTROLLERCONFIG environment variable.
+type Gamepads struct {
+	controllers map[sdl.JoystickID]*sdl.GameController
+	mapper      *input.Mapper
+}
+
+// NewGamepads initialises the SDL game controller subsystem. Controllers
+// already connected are opened when their device added events are pumped.
+func NewGamepads(padmap input.Keymap) (*Gamepads, error) {
+	if err := sdl.InitSubSystem(sdl.INIT_GAMECONTROLLER); err != nil {
+		return nil, err
+	}
+
+	for name := range padmap {
+		if !validPadInput(name) {
+			return nil, fmt.Errorf("unknown gamepad input %q", name)
+		}
+	}
+
+	return &Gamepads{
+		controllers: map[sdl.JoystickID]*sdl.GameController{},
+		mapper:      input.NewMapper(padmap),
+	}, nil
+}
+
+func validPadInput(name string) bool {
+	if strings.HasSuffix(name, "+") || strings.HasSuffix(name, "-") {
+		return sdl.GameControllerGetAxisFromString(name[:len(name)-1]) != sdl.CONTROLLER_AXIS_INVALID
+	}
+	return sdl.GameControllerGetButtonFromString(name) != sdl.CONTROLLER_BUTTON_INVALID
+}
+
+func (g *Gamepads) HandleEvent(e sdl.Event) {
+	switch e := e.(type) {
+	case *sdl.ControllerDeviceEvent:
+		switch e.Type {
+		case sdl.CONTROLLERDEVICEADDED:
+			g.open(int(e.Which))
+		case sdl.CONTROLLERDEVICEREMOVED:
+			g.close(e.Which)
+		}
+	case *sdl.ControllerButtonEvent:
+		name := sdl.GameControllerGetStringForButton(sdl.GameControllerButton(e.Button))
+		g.mapper.Set(int(e.Which), name, e.State == sdl.PRESSED)
+	case *sdl.ControllerAxisEvent:
+		name := sdl.GameControllerGetStringForAxis(sdl.GameControllerAxis(e.Axis))
+		g.mapper.Set(int(e.Which), name+"-", e.Value < -axisDeadzone)
+		g.mapper.Set(int(e.Which), name+"+", e.Value > axisDeadzone)
+	case *sdl.WindowEvent:
+		// Like keys, buttons released while the window is unfocused are
+		// never reported
+		if e.Event == sdl.WINDOWEVENT_FOCUS_LOST {
+			g.mapper.ReleaseAll()
+		}
+	}
+}
+
+func (g *Gamepads) open(index int) {
+	if !sdl.IsGameController(index) {
+		return
+	}
+	c := sdl.GameControllerOpen(index)
+	if c == nil {
+		return
+	}
+	g.controllers[c.Joystick().InstanceID()] = c
+}
+
+func (g *Gamepads) close(id sdl.JoystickID) {
+	if c, ok := g.controllers[id]; ok {
+		c.Close()
+		delete(g.controllers, id)
+	}
+	g.mapper.ReleaseSource(int(id))
+}
+
+func (g *Gamepads) IsDown(key uint8) bool {
+	return g.mapper.IsDown(key)
+}
+
+func (g *Gamepads) Dispose() {
+	for id := range g.controllers {
+		g.close(id)
+	}
+	sdl.QuitSubSystem(sdl.INIT_GAMECONTROLLER)
+}
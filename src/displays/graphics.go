@@ -0,0 +1,115 @@
+package displays
+
+import (
+	"bufio"
+	"bytes"
+	"chip8/src/chip8"
+	"chip8/src/terminal"
+	"chip8/src/video"
+	"fmt"
+	"io"
+	"os"
+)
+
+// GraphicsDisplay draws real bitmaps in terminals that support the sixel or
+// kitty graphics protocol. The keyboard, resizing and quitting work like in
+// the text display.
+type GraphicsDisplay struct {
+	*TextDisplay
+
+	protocol string
+	video    *video.Renderer
+	scale    int
+	out      *bufio.Writer
+
+	last          []uint8
+	width, height int
+	notice        string
+	status        string
+}
+
+// NewGraphicsDisplay opens the terminal for the protocol terminal.Sixel or
+// terminal.Kitty. Sixel images have every pixel enlarged to scale by scale
+// screen pixels, kitty images are scaled to the terminal by the terminal.
+func NewGraphicsDisplay(protocol string, screen *video.Renderer, scale int) (*GraphicsDisplay, error) {
+	if protocol != terminal.Sixel && protocol != terminal.Kitty {
+		return nil, fmt.Errorf("unknown graphics protocol %q", protocol)
+	}
+
+	text, err := NewTextDisplay()
+	if err != nil {
+		return nil, err
+	}
+
+	return &GraphicsDisplay{
+		TextDisplay: text,
+		protocol:    protocol,
+		video:       screen,
+		scale:       scale,
+		out:         bufio.NewWriter(os.Stdout),
+	}, nil
+}
+
+func (g *GraphicsDisplay) Dispose() {
+	if g.protocol == terminal.Kitty {
+		_ = terminal.ClearKitty(os.Stdout)
+	}
+	g.TextDisplay.Dispose()
+}
+
+// Render draws the screen if it changed, the terminal was resized or the
+// notification changed. It is meant to be called once per frame.
+func (g *GraphicsDisplay) Render() {
+	img := g.video.Render(g.Pixels[:], chip8.ScreenWidth, chip8.ScreenHeight)
+
+	width, height := g.screen.Size()
+	notice := g.currentNotice()
+	if bytes.Equal(img.Pix, g.last) && width == g.width && height == g.height && notice == g.notice {
+		// The status line changes often and does not need the image redrawn
+		if g.status != g.TextDisplay.status {
+			g.status = g.TextDisplay.status
+			g.drawStatus(true)
+			g.screen.Show()
+		}
+		return
+	}
+	g.last = append(g.last[:0], img.Pix...)
+	g.width, g.height = width, height
+	g.notice = notice
+
+	// The cells under the image stay empty, tcell only has to clear them
+	// after a resize and draw the status line and notification
+	g.status = g.TextDisplay.status
+	g.drawStatus(true)
+	g.drawNotice(true)
+	g.screen.Show()
+
+	var err error
+	if g.protocol == terminal.Kitty {
+		// Cells are about twice as high as wide, so four columns per row
+		// keep the 2:1 aspect ratio. The top row is left for the status line
+		// and the bottom row for notifications.
+		rows := height - 2
+		if width/4 < rows {
+			rows = width / 4
+		}
+		cols := rows * 4
+		moveCursor(g.out, (width-cols)/2, (height-rows)/2)
+		err = terminal.WriteKitty(g.out, img, cols, rows)
+	} else {
+		moveCursor(g.out, 0, 1)
+		err = terminal.WriteSixel(g.out, img, g.scale)
+	}
+	if err == nil {
+		err = g.out.Flush()
+	}
+	if err != nil {
+		g.drawText(0, 0, err.Error())
+		g.screen.Show()
+	}
+}
+
+// moveCursor moves the terminal cursor to the zero based column x and row y
+func moveCursor(w io.Writer, x int, y int) {
+	fmt.Fprintf(w, "\x1b[%d;%dH", y+1, x+1)
+}
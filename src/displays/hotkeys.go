@@ -0,0 +1,140 @@
+package displays
+
+import (
+	"chip8/src/input"
+	"chip8/src/terminal"
+	"github.com/gdamore/tcell"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NoticeDuration is how long a notification stays on the screen
+const NoticeDuration = 2 * time.Second
+
+// terminalKey is a key as tcell reports it, rune is only set for tcell.KeyRune
+type terminalKey struct {
+	key tcell.Key
+	r   rune
+	alt bool
+}
+
+var terminalKeyNames = map[string]tcell.Key{
+	"tab":       tcell.KeyTab,
+	"return":    tcell.KeyEnter,
+	"enter":     tcell.KeyEnter,
+	"backspace": tcell.KeyBackspace2,
+	"up":        tcell.KeyUp,
+	"down":      tcell.KeyDown,
+	"left":      tcell.KeyLeft,
+	"right":     tcell.KeyRight,
+	"home":      tcell.KeyHome,
+	"end":       tcell.KeyEnd,
+	"pageup":    tcell.KeyPgUp,
+	"pagedown":  tcell.KeyPgDn,
+	"insert":    tcell.KeyInsert,
+	"delete":    tcell.KeyDelete,
+}
+
+// parseTerminalKey translates a hotkey name into what tcell reports for it.
+// Terminals cannot report every combination: Shift only works with function
+// keys, Ctrl only with letters and Alt only with characters.
+func parseTerminalKey(name string) (terminalKey, bool) {
+	c := input.ParseChord(name)
+	lower := strings.ToLower(c.Key)
+
+	if strings.HasPrefix(lower, "f") && !c.Ctrl && !c.Alt {
+		n, err := strconv.Atoi(lower[1:])
+		if err == nil && n >= 1 && n <= 12 {
+			// Terminals report Shift+F1 as F13 and so on
+			if c.Shift {
+				n += 12
+			}
+			return terminalKey{key: tcell.KeyF1 + tcell.Key(n-1)}, true
+		}
+	}
+	if c.Shift {
+		return terminalKey{}, false
+	}
+
+	if lower == "space" {
+		lower = " "
+	}
+	r, size := utf8.DecodeRuneInString(lower)
+	if size == len(lower) && r != utf8.RuneError {
+		switch {
+		case c.Ctrl && r >= 'a' && r <= 'z' && !c.Alt:
+			return terminalKey{key: tcell.KeyCtrlA + tcell.Key(r-'a')}, true
+		case !c.Ctrl:
+			return terminalKey{key: tcell.KeyRune, r: r, alt: c.Alt}, true
+		}
+		return terminalKey{}, false
+	}
+
+	key, ok := terminalKeyNames[lower]
+	if !ok || c.Ctrl || c.Alt {
+		return terminalKey{}, false
+	}
+	return terminalKey{key: key}, true
+}
+
+// eventKey returns the hotkey of a tcell event
+func eventKey(e *tcell.EventKey) terminalKey {
+	switch e.Key() {
+	case tcell.KeyRune:
+		return terminalKey{key: tcell.KeyRune, r: unicode.ToLower(e.Rune()), alt: e.Modifiers()&tcell.ModAlt != 0}
+	case tcell.KeyBackspace:
+		return terminalKey{key: tcell.KeyBackspace2}
+	}
+	return terminalKey{key: e.Key()}
+}
+
+// terminalHotkeys emulates releasing hotkeys like terminal.Keyboard does for
+// the keypad. Every press and repeat is passed on, the release follows once
+// the repeats stop.
+type terminalHotkeys struct {
+	keys     map[terminalKey]input.Action
+	releases map[input.Action]time.Time
+	handle   func(a input.Action, down bool)
+}
+
+func (h *terminalHotkeys) press(k terminalKey) bool {
+	action, ok := h.keys[k]
+	if !ok {
+		return false
+	}
+
+	release := time.Now().Add(terminal.DefaultRelease)
+	if _, held := h.releases[action]; !held {
+		release = time.Now().Add(terminal.DefaultFirstRelease)
+	}
+	h.releases[action] = release
+	h.handle(action, true)
+	return true
+}
+
+// releaseExpired releases the hotkeys that were not repeated in time
+func (h *terminalHotkeys) releaseExpired() {
+	now := time.Now()
+	for action, release := range h.releases {
+		if now.After(release) {
+			delete(h.releases, action)
+			h.handle(action, false)
+		}
+	}
+}
+
+// sameLines reports whether a and b hold the same lines of text
+func sameLines(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,163 +1,346 @@
 package displays
 
 import (
+	"chip8/src/chip8"
+	"chip8/src/input"
+	"chip8/src/video"
 	"fmt"
 	"github.com/veandco/go-sdl2/sdl"
 	"time"
 )
 
-var binds = map[sdl.Scancode]uint8{
-	sdl.SCANCODE_1: 1,
-	sdl.SCANCODE_2: 2,
-	sdl.SCANCODE_3: 3,
-	sdl.SCANCODE_Q: 4,
-	sdl.SCANCODE_W: 5,
-	sdl.SCANCODE_E: 6,
-	sdl.SCANCODE_A: 7,
-	sdl.SCANCODE_S: 8,
-	sdl.SCANCODE_D: 9,
-	sdl.SCANCODE_Z: 0x0A,
-	sdl.SCANCODE_X: 0,
-	sdl.SCANCODE_C: 0x0B,
-	sdl.SCANCODE_4: 0x0C,
-	sdl.SCANCODE_R: 0x0D,
-	sdl.SCANCODE_F: 0x0E,
-	sdl.SCANCODE_V: 0x0F,
-}
-
 type NewSDLDisplay struct {
+	chip8.Framebuffer
+
 	window    *sdl.Window
 	renderer  *sdl.Renderer
+	texture   *sdl.Texture
+	video     *video.Renderer
 	pixelSize int32
 
-	Memory       [4096]bool
+	// shown is the screen of the last Render, renderNeeded is set when it has
+	// to be drawn again for other reasons
+	shown        [chip8.ScreenWidth * chip8.ScreenHeight]bool
 	renderNeeded bool
+
+	binds    map[sdl.Keycode]uint8
+	keypad   input.Keypad
+	handlers []EventHandler
+
+	hotkeys  map[sdlChord]input.Action
+	held     map[sdl.Keycode]input.Action
+	onHotkey func(a input.Action, down bool)
+
+	notice      string
+	noticeUntil time.Time
+	noticeShown bool
+	status      []string
+
+	quit       bool
+	unfocused  bool
+	minimised  bool
+	fullscreen bool
 }
 
-func (t *NewSDLDisplay) IsDown(key uint8) bool {
-	keys := sdl.GetKeyboardState()
+// EventHandler receives every SDL event pumped by the display
+type EventHandler interface {
+	HandleEvent(e sdl.Event)
+}
+
+// AddEventHandler makes PumpEvents forward events to h
+func (t *NewSDLDisplay) AddEventHandler(h EventHandler) {
+	t.handlers = append(t.handlers, h)
+}
 
-	for _, i := range keys {
-		k := sdl.GetScancodeFromKey(sdl.Keycode(i))
-		if binds[k] == key {
-			return true
+// SetKeymap replaces the key bindings. Names SDL does not know are reported as an error.
+func (t *NewSDLDisplay) SetKeymap(keymap input.Keymap) error {
+	binds := map[sdl.Keycode]uint8{}
+	for name, key := range keymap {
+		code := sdl.GetKeyFromName(name)
+		if code == sdl.K_UNKNOWN {
+			return fmt.Errorf("unknown key name %q", name)
 		}
+		binds[code] = key
 	}
 
-	return false
-}
-
-func (t *NewSDLDisplay) WaitForKey() uint8 {
-	for true {
-		fmt.Println("Waiting for key")
-		switch s := sdl.PollEvent().(type) {
-		case *sdl.KeyboardEvent:
-			if s.State != sdl.PRESSED {
-				break
-			}
-			for key, v := range binds {
-				if s.Keysym.Scancode == key {
-					return v
-				}
-			}
-			break
-		default:
-			time.Sleep(10 * time.Millisecond)
+	t.binds = binds
+	t.keypad.ReleaseAll()
+	return nil
+}
+
+// sdlChord is a key code together with the modifiers of input.Chord
+type sdlChord struct {
+	code             sdl.Keycode
+	shift, ctrl, alt bool
+}
+
+// SetHotkeys binds the hotkeys, which take precedence over the keypad. Every
+// press and release of a hotkey is passed to handle.
+func (t *NewSDLDisplay) SetHotkeys(hotkeys input.Hotkeys, handle func(a input.Action, down bool)) error {
+	chords := map[sdlChord]input.Action{}
+	for action, name := range hotkeys {
+		c := input.ParseChord(name)
+		code := sdl.GetKeyFromName(c.Key)
+		if code == sdl.K_UNKNOWN {
+			return fmt.Errorf("unknown key name %q for %s", c.Key, action)
 		}
+		chords[sdlChord{code, c.Shift, c.Ctrl, c.Alt}] = action
 	}
 
-	panic("Should never happen")
+	t.hotkeys = chords
+	t.held = map[sdl.Keycode]input.Action{}
+	t.onHotkey = handle
+	return nil
+}
+
+// Notify shows text on the screen for NoticeDuration
+func (t *NewSDLDisplay) Notify(text string) {
+	t.notice = text
+	t.noticeUntil = time.Now().Add(NoticeDuration)
+	t.renderNeeded = true
 }
 
-func (t *NewSDLDisplay) Clear() {
-	rect := sdl.Rect{
-		X: 0,
-		Y: 0,
-		W: 64 * t.pixelSize,
-		H: 32 * t.pixelSize,
+// SetStatus shows lines of text in the top left corner of the screen until
+// it is called with no lines
+func (t *NewSDLDisplay) SetStatus(lines []string) {
+	if !sameLines(lines, t.status) {
+		t.status = append(t.status[:0], lines...)
+		t.renderNeeded = true
 	}
-	_ = t.renderer.FillRect(&rect)
-	t.renderer.Present()
 }
 
-func (t *NewSDLDisplay) Dispose() {
-	_ = t.window.Destroy()
-	_ = t.renderer.Destroy()
+// PumpEvents drains the SDL event queue and updates the keypad. It must be
+// called regularly by the emulator loop.
+func (t *NewSDLDisplay) PumpEvents() {
+	for e := sdl.PollEvent(); e != nil; e = sdl.PollEvent() {
+		t.handleEvent(e)
+	}
 }
 
-func (t *NewSDLDisplay) SetSprite(x uint8, y uint8, sprites []uint8) bool {
-	c := false
-	for i, sprite := range sprites {
-		for i2, bit := range bits {
-			if t.SetPixel(x+uint8(i2), y+uint8(i), sprite&bit == bit) {
-				c = true
-			}
-		}
+// QuitRequested reports whether the window was closed or SDL asked the
+// program to quit
+func (t *NewSDLDisplay) QuitRequested() bool {
+	return t.quit
+}
+
+// Paused reports whether the window is minimised or has lost keyboard focus.
+// The emulator loop should not advance while it is paused.
+func (t *NewSDLDisplay) Paused() bool {
+	return t.unfocused || t.minimised
+}
+
+// ToggleFullscreen switches between a desktop sized fullscreen window and
+// the normal window
+func (t *NewSDLDisplay) ToggleFullscreen() error {
+	var flags uint32
+	if !t.fullscreen {
+		flags = sdl.WINDOW_FULLSCREEN_DESKTOP
+	}
+	if err := t.window.SetFullscreen(flags); err != nil {
+		return err
+	}
+
+	t.fullscreen = !t.fullscreen
+	t.renderNeeded = true
+	return nil
+}
+
+func (t *NewSDLDisplay) handleEvent(e sdl.Event) {
+	for _, h := range t.handlers {
+		h.HandleEvent(e)
 	}
 
-	return c
+	switch e := e.(type) {
+	case *sdl.QuitEvent:
+		t.quit = true
+	case *sdl.WindowEvent:
+		t.handleWindowEvent(e)
+	case *sdl.KeyboardEvent:
+		t.handleKeyboardEvent(e)
+	}
 }
 
-func (t *NewSDLDisplay) GetPixel(x uint8, y uint8) bool {
-	return t.Memory[memoryLocation(x, y)]
+func (t *NewSDLDisplay) handleWindowEvent(e *sdl.WindowEvent) {
+	switch e.Event {
+	case sdl.WINDOWEVENT_CLOSE:
+		t.quit = true
+	case sdl.WINDOWEVENT_FOCUS_LOST:
+		// Release events are not delivered to unfocused windows, so keys
+		// held while focus moves away would otherwise stay down
+		t.unfocused = true
+		t.keypad.ReleaseAll()
+	case sdl.WINDOWEVENT_FOCUS_GAINED:
+		t.unfocused = false
+	case sdl.WINDOWEVENT_MINIMIZED:
+		t.minimised = true
+	case sdl.WINDOWEVENT_RESTORED, sdl.WINDOWEVENT_MAXIMIZED:
+		t.minimised = false
+		t.renderNeeded = true
+	case sdl.WINDOWEVENT_SIZE_CHANGED, sdl.WINDOWEVENT_EXPOSED:
+		t.renderNeeded = true
+	}
 }
 
-func (t *NewSDLDisplay) SetPixel(x uint8, y uint8, on bool) bool {
-	mLoc := memoryLocation(x, y)
-	c := false
+func (t *NewSDLDisplay) handleKeyboardEvent(k *sdl.KeyboardEvent) {
+	if k.Repeat != 0 {
+		return
+	}
 
-	if on && t.Memory[mLoc] {
-		c = true
+	if k.State == sdl.PRESSED && isFullscreenToggle(k.Keysym) {
+		_ = t.ToggleFullscreen()
+		return
+	}
+	if t.handleHotkey(k) {
+		return
 	}
 
-	t.renderNeeded = t.renderNeeded || on != t.Memory[mLoc]
-	if on {
-		t.Memory[mLoc] = !t.Memory[mLoc]
+	key, bound := t.binds[k.Keysym.Sym]
+	if !bound {
+		return
 	}
 
-	return c
+	if k.State == sdl.PRESSED {
+		t.keypad.Press(key)
+	} else {
+		t.keypad.Release(key)
+	}
 }
 
+// handleHotkey reports whether k presses or releases a hotkey. Releases are
+// matched by key alone, the modifiers may have been released first.
+func (t *NewSDLDisplay) handleHotkey(k *sdl.KeyboardEvent) bool {
+	if k.State != sdl.PRESSED {
+		action, ok := t.held[k.Keysym.Sym]
+		if ok {
+			delete(t.held, k.Keysym.Sym)
+			t.onHotkey(action, false)
+		}
+		return ok
+	}
+
+	mod := k.Keysym.Mod
+	action, ok := t.hotkeys[sdlChord{
+		code:  k.Keysym.Sym,
+		shift: mod&sdl.KMOD_SHIFT != 0,
+		ctrl:  mod&sdl.KMOD_CTRL != 0,
+		alt:   mod&sdl.KMOD_ALT != 0,
+	}]
+	if ok {
+		t.held[k.Keysym.Sym] = action
+		t.onHotkey(action, true)
+	}
+	return ok
+}
+
+// isFullscreenToggle reports whether key is F11 or Alt+Enter
+func isFullscreenToggle(key sdl.Keysym) bool {
+	return key.Sym == sdl.K_F11 || key.Sym == sdl.K_RETURN && key.Mod&sdl.KMOD_ALT != 0
+}
+
+func (t *NewSDLDisplay) IsDown(key uint8) bool {
+	return t.keypad.IsDown(key)
+}
+
+// Dispose destroys the renderer and window and shuts SDL down
+func (t *NewSDLDisplay) Dispose() {
+	_ = t.texture.Destroy()
+	_ = t.renderer.Destroy()
+	_ = t.window.Destroy()
+	sdl.Quit()
+}
+
+// Render uploads the screen to the window if it changed. It is meant to be
+// called once per frame.
 func (t *NewSDLDisplay) Render() {
-	if !t.renderNeeded {
+	notice := time.Now().Before(t.noticeUntil)
+	if !t.renderNeeded && t.Pixels == t.shown && !t.video.Animating() && notice == t.noticeShown {
 		return
 	}
-	_ = t.renderer.Clear()
-	for x := 0; x < 64; x++ {
-		for y := 0; y < 32; y++ {
-			rect := sdl.Rect{
-				X: int32(x) % 64 * t.pixelSize,
-				Y: int32(y) * t.pixelSize,
-				W: t.pixelSize,
-				H: t.pixelSize,
-			}
-
-			if t.GetPixel(uint8(x), uint8(y)) {
-				_ = t.renderer.SetDrawColor(0xFF, 0xFF, 0xFF, 0xFF)
-			} else {
-				_ = t.renderer.SetDrawColor(0x00, 0x00, 0x00, 0xFF)
-			}
-			_ = t.renderer.FillRect(&rect)
 
-		}
+	t.shown = t.Pixels
+	img := t.video.Render(t.Pixels[:], chip8.ScreenWidth, chip8.ScreenHeight)
+	if notice {
+		t.video.DrawNotice(img, t.notice)
 	}
+	t.noticeShown = notice
+	t.video.DrawOverlay(img, t.status)
+	_ = t.texture.Update(nil, img.Pix, img.Stride)
+	_ = t.renderer.SetDrawColor(0x00, 0x00, 0x00, 0xFF)
+	_ = t.renderer.Clear()
+	_ = t.renderer.Copy(t.texture, nil, nil)
 	t.renderer.Present()
 	t.renderNeeded = false
 }
 
+// SetPalette changes the colours of the screen
+func (t *NewSDLDisplay) SetPalette(p video.Palette) {
+	t.video.Palette = p
+	t.renderNeeded = true
+}
+
+// SetPersistence enables the phosphor effect, nil turns it off
+func (t *NewSDLDisplay) SetPersistence(p *video.Persistence) {
+	t.video.Persistence = p
+	t.renderNeeded = true
+}
+
+// SetFilter changes the upscaler used before the screen is scaled to the window
+func (t *NewSDLDisplay) SetFilter(f video.Filter) error {
+	t.video.Filter = f
+	w, h := t.video.Size(chip8.ScreenWidth, chip8.ScreenHeight)
+
+	texture, err := t.renderer.CreateTexture(uint32(sdl.PIXELFORMAT_RGBA32), sdl.TEXTUREACCESS_STREAMING, int32(w), int32(h))
+	if err != nil {
+		return err
+	}
+	if t.texture != nil {
+		_ = t.texture.Destroy()
+	}
+	t.texture = texture
+
+	// SDL scales the texture to the window and letterboxes it to keep the
+	// 2:1 aspect ratio
+	if err := t.renderer.SetLogicalSize(int32(w), int32(h)); err != nil {
+		return err
+	}
+	t.renderNeeded = true
+	return nil
+}
+
+// SetIntegerScaling limits scaling to whole multiples of the screen size
+// instead of filling as much of the window as possible
+func (t *NewSDLDisplay) SetIntegerScaling(on bool) error {
+	t.renderNeeded = true
+	return t.renderer.SetIntegerScale(on)
+}
+
 func NewSDLRenderer(pixelSize int32) (*NewSDLDisplay, error) {
-	window, err := sdl.CreateWindow("Chip8", sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED, 64*pixelSize, 32*pixelSize, sdl.WINDOW_SHOWN)
+	window, err := sdl.CreateWindow("Chip8", sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED, 64*pixelSize, 32*pixelSize, sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE)
 	if err != nil {
 		return nil, err
 	}
 
 	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
 	if err != nil {
+		_ = window.Destroy()
 		return nil, err
 	}
-	return &NewSDLDisplay{
+
+	display := &NewSDLDisplay{
 		pixelSize: pixelSize,
 		window:    window,
 		renderer:  renderer,
-	}, nil
+		video:     video.NewRenderer(video.Palettes[video.DefaultPalette], video.NoFilter),
+	}
+	if err := display.SetFilter(video.NoFilter); err != nil {
+		_ = renderer.Destroy()
+		_ = window.Destroy()
+		return nil, err
+	}
+	keymap, _ := input.Preset(input.DefaultPreset)
+	if err := display.SetKeymap(keymap); err != nil {
+		return nil, err
+	}
+
+	return display, nil
 }
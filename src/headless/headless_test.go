@@ -0,0 +1,131 @@
+package headless
+
+import (
+	"bytes"
+	"chip8/src/chip8"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestParseKeyScript(t *testing.T) {
+	events, err := ParseKeyScript("30:5,10:a,40:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k := ScriptedKeyboard{Events: events}
+	for _, c := range []struct {
+		frame uint64
+		key   uint8
+		down  bool
+	}{
+		{0, 0xA, false},
+		{10, 0xA, true},
+		{30, 0xA, false},
+		{30, 0x5, true},
+		{40, 0x5, false},
+	} {
+		k.SetFrame(c.frame)
+		if k.IsDown(c.key) != c.down {
+			t.Errorf("Expected key %x down=%v at frame %d", c.key, c.down, c.frame)
+		}
+	}
+
+	if _, err := ParseKeyScript("10:g"); err == nil {
+		t.Errorf("Expected an invalid key to fail")
+	}
+}
+
+var keyProgram = []byte{
+	0x60, 0x05, // 0x200 LD V0, 5
+	0xE0, 0x9E, // 0x202 SKP V0
+	0x12, 0x0C, // 0x204 JP 0x20C
+	0xA0, 0x00, // 0x206 LD I, 0 (glyph 0)
+	0xD1, 0x15, // 0x208 DRW V1, V1, 5
+	0x12, 0x0A, // 0x20A JP 0x20A
+	0x12, 0x0C, // 0x20C JP 0x20C
+}
+
+func TestRunner_Screenshot(t *testing.T) {
+	r := NewRunner(0xFFF, []KeyEvent{{Frame: 1, Keys: []uint8{0x5}}})
+	_ = r.Cpu.LoadProgram(bytes.NewReader(keyProgram))
+	r.RunFrames(2)
+	if r.Display.GetPixel(0, 0) {
+		t.Errorf("Expected nothing to be drawn while key 5 is up")
+	}
+
+	r = NewRunner(0xFFF, []KeyEvent{{Frame: 0, Keys: []uint8{0x5}}})
+	_ = r.Cpu.LoadProgram(bytes.NewReader(keyProgram))
+	if !r.RunFrames(2) {
+		t.Fatalf("Expected the program to keep running")
+	}
+
+	if !r.Display.GetPixel(0, 0) || !r.Display.GetPixel(3, 4) || r.Display.GetPixel(1, 1) {
+		t.Errorf("Expected the glyph for 0 to be drawn")
+	}
+
+	out := bytes.Buffer{}
+	if err := WritePNG(&out, r.Display, 4); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 256 || img.Bounds().Dy() != 128 {
+		t.Errorf("Expected a 256x128 image, got %v", img.Bounds())
+	}
+	if r, _, _, _ := img.At(1, 1).RGBA(); r == 0 {
+		t.Errorf("Expected the top left pixel to be lit")
+	}
+}
+
+func TestRunner_SkipIdle(t *testing.T) {
+	var runners []*Runner
+	for _, skip := range []bool{false, true} {
+		r := NewRunner(0xFFF, []KeyEvent{{Frame: 0, Keys: []uint8{0x5}}})
+		r.SkipIdle = skip
+		_ = r.Cpu.LoadProgram(bytes.NewReader(keyProgram))
+		r.RunFrames(3)
+		runners = append(runners, r)
+	}
+
+	if diff := chip8.CompareState(runners[0].Cpu, runners[1].Cpu); len(diff) > 0 {
+		t.Errorf("skipping the final loop changed %v", diff)
+	}
+	if runners[1].Display.Pixels != runners[0].Display.Pixels {
+		t.Errorf("skipping the final loop changed the screen")
+	}
+
+	// Single steps are never skipped
+	r := runners[1]
+	r.Step()
+	if r.step != 1 {
+		t.Errorf("Step ran %d instructions", r.step)
+	}
+}
+
+func TestWriteImagePNG(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.SetRGBA(1, 0, color.RGBA{R: 0xFF, A: 0xFF})
+
+	out := bytes.Buffer{}
+	if err := WriteImagePNG(&out, src, 3); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 6 || img.Bounds().Dy() != 3 {
+		t.Errorf("Expected a 6x3 image, got %v", img.Bounds())
+	}
+	if r, _, _, _ := img.At(3, 2).RGBA(); r != 0xFFFF {
+		t.Errorf("Expected the right half to be red")
+	}
+	if r, _, _, _ := img.At(2, 2).RGBA(); r != 0 {
+		t.Errorf("Expected the left half to be black")
+	}
+}
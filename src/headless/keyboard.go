@@ -0,0 +1,77 @@
+package headless
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// KeyEvent sets the keys held down from Frame onwards
+type KeyEvent struct {
+	Frame uint64
+	Keys  []uint8
+}
+
+// ScriptedKeyboard is a chip8.Keyboard driven by a list of key events
+type ScriptedKeyboard struct {
+	Events []KeyEvent
+
+	frame uint64
+	down  []uint8
+}
+
+// ParseKeyScript parses a script like "30:5,32:,60:4a" where each entry
+// holds the given hex keys from that frame until the next entry.
+func ParseKeyScript(script string) ([]KeyEvent, error) {
+	var events []KeyEvent
+	if strings.TrimSpace(script) == "" {
+		return events, nil
+	}
+
+	for _, entry := range strings.Split(script, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid key event %q, expected frame:keys", entry)
+		}
+
+		frame, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid frame in %q: %s", entry, err)
+		}
+
+		e := KeyEvent{Frame: frame}
+		for _, c := range parts[1] {
+			key, err := strconv.ParseUint(string(c), 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key %q in %q", c, entry)
+			}
+			e.Keys = append(e.Keys, uint8(key))
+		}
+		events = append(events, e)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Frame < events[j].Frame })
+	return events, nil
+}
+
+// SetFrame applies every event up to and including frame
+func (s *ScriptedKeyboard) SetFrame(frame uint64) {
+	s.frame = frame
+	s.down = nil
+	for _, e := range s.Events {
+		if e.Frame > frame {
+			break
+		}
+		s.down = e.Keys
+	}
+}
+
+func (s *ScriptedKeyboard) IsDown(key uint8) bool {
+	for _, k := range s.down {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
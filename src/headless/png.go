@@ -0,0 +1,55 @@
+package headless
+
+import (
+	"chip8/src/chip8"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+var (
+	Background = color.RGBA{A: 0xFF}
+	Foreground = color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+)
+
+// Image converts the framebuffer into an image where every pixel is scale by scale
+func Image(f *chip8.Framebuffer, scale int) *image.Paletted {
+	if scale < 1 {
+		scale = 1
+	}
+	img := image.NewPaletted(
+		image.Rect(0, 0, chip8.ScreenWidth*scale, chip8.ScreenHeight*scale),
+		color.Palette{Background, Foreground},
+	)
+
+	for y := 0; y < chip8.ScreenHeight*scale; y++ {
+		for x := 0; x < chip8.ScreenWidth*scale; x++ {
+			if f.GetPixel(uint8(x/scale), uint8(y/scale)) {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	return img
+}
+
+// WritePNG encodes the framebuffer as a PNG
+func WritePNG(w io.Writer, f *chip8.Framebuffer, scale int) error {
+	return png.Encode(w, Image(f, scale))
+}
+
+// WriteImagePNG encodes an image rendered by the video package, every pixel
+// enlarged to a scale by scale block
+func WriteImagePNG(w io.Writer, img *image.RGBA, scale int) error {
+	if scale < 1 {
+		scale = 1
+	}
+	bounds := img.Bounds()
+	scaled := image.NewRGBA(image.Rect(0, 0, bounds.Dx()*scale, bounds.Dy()*scale))
+	for y := 0; y < scaled.Rect.Dy(); y++ {
+		for x := 0; x < scaled.Rect.Dx(); x++ {
+			scaled.SetRGBA(x, y, img.RGBAAt(bounds.Min.X+x/scale, bounds.Min.Y+y/scale))
+		}
+	}
+	return png.Encode(w, scaled)
+}
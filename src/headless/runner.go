@@ -0,0 +1,90 @@
+package headless
+
+import (
+	"chip8/src/chip8"
+)
+
+// FrameKeyboard is a keyboard whose state only changes between frames
+type FrameKeyboard interface {
+	chip8.Keyboard
+	SetFrame(frame uint64)
+}
+
+// Runner executes a cpu without any window or terminal
+type Runner struct {
+	Cpu      *chip8.Cpu
+	Display  *chip8.Framebuffer
+	Keyboard FrameKeyboard
+	// InstructionsPerFrame is the number of instructions between timer ticks
+	InstructionsPerFrame int
+	// SkipIdle lets RunFrames skip idle loops up to the next timer tick, see chip8.Cpu.SkipIdle
+	SkipIdle bool
+
+	Frame uint64
+	// step is the number of instructions run in the current frame
+	step int
+}
+
+// NewRunner creates a cpu with a framebuffer display and a scripted keyboard
+func NewRunner(memorySize int16, events []KeyEvent) *Runner {
+	return NewRunnerWithKeyboard(memorySize, &ScriptedKeyboard{Events: events})
+}
+
+// NewRunnerWithKeyboard creates a cpu with a framebuffer display reading input from keyboard
+func NewRunnerWithKeyboard(memorySize int16, keyboard FrameKeyboard) *Runner {
+	display := &chip8.Framebuffer{}
+	cpu := chip8.NewCPU(memorySize, display, keyboard)
+
+	return &Runner{
+		Cpu:                  &cpu,
+		Display:              display,
+		Keyboard:             keyboard,
+		InstructionsPerFrame: 10,
+		SkipIdle:             true,
+	}
+}
+
+// Step runs a single instruction and ticks the timers after every
+// InstructionsPerFrame instructions. It returns false if the program counter
+// left memory.
+func (r *Runner) Step() bool {
+	return r.advance(false)
+}
+
+// advance runs one instruction or, if idle is set and the cpu is idle, all
+// instructions up to the end of the frame
+func (r *Runner) advance(idle bool) bool {
+	if r.step == 0 {
+		r.Keyboard.SetFrame(r.Frame)
+	}
+	if int(r.Cpu.PC)+1 >= len(r.Cpu.Memory) {
+		return false
+	}
+
+	n := 0
+	if idle && r.SkipIdle {
+		n = r.Cpu.SkipIdle(r.InstructionsPerFrame - r.step)
+	}
+	if n == 0 {
+		r.Cpu.Step()
+		n = 1
+	}
+	r.step += n
+	if r.step >= r.InstructionsPerFrame {
+		r.Cpu.DecrementTimers()
+		r.Frame++
+		r.step = 0
+	}
+	return true
+}
+
+// RunFrames runs until n more frames have finished. It returns false if the
+// program counter left memory before all frames were run.
+func (r *Runner) RunFrames(n uint64) bool {
+	for end := r.Frame + n; r.Frame < end; {
+		if !r.advance(true) {
+			return false
+		}
+	}
+	return true
+}
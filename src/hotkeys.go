@@ -0,0 +1,220 @@
+package main
+
+import (
+	"chip8/src/chip8"
+	"chip8/src/headless"
+	"chip8/src/input"
+	"chip8/src/machine"
+	"chip8/src/savestate"
+	"chip8/src/video"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// screenshotScale is the size of a screen pixel in screenshots taken with a hotkey
+const screenshotScale = 8
+
+// hotkeys carries out hotkey actions. All of its methods run on the
+// goroutine of the machine.
+type hotkeys struct {
+	window      window
+	screen      *video.Renderer
+	romPath     string
+	romHash     string
+	fastForward float64
+	states      savestate.Store
+	// recording is set while a movie is recorded, which loading a state
+	// would break by going back in time
+	recording bool
+
+	turbo, held bool
+	showStats   bool
+}
+
+func (h *hotkeys) handle(m *machine.Machine, a input.Action, down bool) {
+	if a == input.FastForwardHold {
+		h.held = down
+		h.updateSpeed(m)
+		if down {
+			h.window.Notify("Fast forward")
+		}
+		return
+	}
+	if !down {
+		return
+	}
+
+	if slot, save, ok := a.StateSlot(); ok {
+		h.state(m, slot, save)
+		return
+	}
+
+	switch a {
+	case input.Pause:
+		if m.IsPaused() {
+			m.Resume()
+			h.window.Notify("Resumed")
+		} else {
+			m.Pause()
+			h.window.Notify("Paused")
+		}
+	case input.FrameAdvance:
+		m.Pause()
+		m.StepFrame()
+		h.window.Notify(fmt.Sprintf("Frame %d", m.Frame+1))
+	case input.Reset:
+		m.Reset(true)
+		h.window.Notify("Reset")
+	case input.FastForward:
+		h.turbo = !h.turbo
+		h.updateSpeed(m)
+		if h.turbo {
+			h.window.Notify("Fast forward on")
+		} else {
+			h.window.Notify("Fast forward off")
+		}
+	case input.Screenshot:
+		h.screenshot(m)
+	case input.ShowStats:
+		h.showStats = !h.showStats
+		h.frame(m)
+	}
+}
+
+func (h *hotkeys) updateSpeed(m *machine.Machine) {
+	if h.turbo || h.held {
+		m.SetSpeed(h.fastForward)
+	} else {
+		m.SetSpeed(1)
+	}
+}
+
+// state saves into or loads from a slot of the rom, counting from 1
+func (h *hotkeys) state(m *machine.Machine, slot int, save bool) {
+	if save {
+		s, err := savestate.New(h.romHash, filepath.Base(h.romPath), m.Snapshot(), time.Now())
+		if err == nil {
+			err = h.states.Save(s, slot)
+		}
+		if err != nil {
+			h.window.Notify(err.Error())
+			return
+		}
+		h.window.Notify(fmt.Sprintf("Saved state %d", slot))
+		return
+	}
+
+	if h.recording {
+		h.window.Notify("Cannot load states while recording")
+		return
+	}
+	s, err := h.states.Load(h.romHash, slot)
+	if os.IsNotExist(err) {
+		h.window.Notify(fmt.Sprintf("State %d is empty", slot))
+		return
+	}
+	if err == nil {
+		err = m.Restore(s.Snapshot)
+	}
+	if err != nil {
+		h.window.Notify(err.Error())
+		return
+	}
+	h.window.Notify(fmt.Sprintf("Loaded state %d", slot))
+}
+
+// screenshot writes the screen into the working directory, named after the rom and the time
+func (h *hotkeys) screenshot(m *machine.Machine) {
+	s := m.Snapshot()
+	name := strings.TrimSuffix(filepath.Base(h.romPath), filepath.Ext(h.romPath))
+	path := fmt.Sprintf("%s-%s.png", name, time.Now().Format("20060102-150405"))
+	if err := writePNG(path, screenImage(h.screen, s.Pixels[:]), screenshotScale); err != nil {
+		h.window.Notify(err.Error())
+		return
+	}
+	h.window.Notify("Screenshot saved")
+}
+
+// frame updates the statistics overlay, it is called after every frame and
+// while paused
+func (h *hotkeys) frame(m *machine.Machine) {
+	if h.showStats {
+		h.window.SetStatus(statsLines(m.Stats()))
+	} else {
+		h.window.SetStatus(nil)
+	}
+}
+
+// statsLines formats the statistics in lines short enough for the overlay
+// of the SDL window
+func statsLines(s machine.Stats) []string {
+	lines := []string{
+		fmt.Sprintf("FPS %.1f", s.FramesPerSecond),
+		"IPS " + shortCount(s.InstructionsPerSecond),
+		fmt.Sprintf("FRAME %.2fMS", float64(s.FrameTime)/float64(time.Millisecond)),
+		fmt.Sprintf("DT %d ST %d", s.DT, s.ST),
+	}
+	if s.WaitingForKey {
+		lines = append(lines, "WAIT KEY")
+	}
+	return lines
+}
+
+// shortCount writes large numbers with a K or M suffix
+func shortCount(n float64) string {
+	switch {
+	case n >= 1e6:
+		return fmt.Sprintf("%.1fM", n/1e6)
+	case n >= 1e4:
+		return fmt.Sprintf("%.1fK", n/1e3)
+	}
+	return fmt.Sprintf("%.0f", n)
+}
+
+// screenImage renders pixels with the palette, upscaler and phosphor effect of screen
+func screenImage(screen *video.Renderer, pixels []bool) *image.RGBA {
+	levels := video.Levels(pixels)
+	if p := screen.Persistence; p != nil && len(p.Levels()) == len(levels) {
+		levels = p.Levels()
+	}
+	return screen.RenderLevels(levels, chip8.ScreenWidth, chip8.ScreenHeight)
+}
+
+func writePNG(path string, img *image.RGBA, scale int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := headless.WriteImagePNG(f, img, scale); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadHotkeys returns the default hotkeys, no hotkeys for "none" or the
+// bindings of a hotkey file on top of the defaults
+func loadHotkeys(spec string) (input.Hotkeys, error) {
+	switch spec {
+	case "", "default":
+		return input.DefaultHotkeys(), nil
+	case "none":
+		return input.Hotkeys{}, nil
+	}
+
+	f, err := os.Open(spec)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h, err := input.ReadHotkeys(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", spec, err)
+	}
+	return h, nil
+}
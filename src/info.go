@@ -0,0 +1,56 @@
+package main
+
+import (
+	"chip8/src/chip8"
+	"chip8/src/movie"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// romInfo implements `chip8 info rom.ch8`, which prints the size and hash of
+// a rom and how often every instruction occurs in it. Data mixed into the
+// code is counted as if it were instructions.
+func romInfo(args []string) int {
+	flags := flag.NewFlagSet("info", flag.ExitOnError)
+	romFlag := flags.String("rom", "", "rom to inspect, instead of the positional argument")
+	_ = flags.Parse(args)
+
+	path, rom, err := readRom(flags, *romFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	fmt.Printf("file:   %s\n", path)
+	fmt.Printf("size:   %d bytes, %#03x-%#03x\n", len(rom), 0x200, 0x200+len(rom)-1)
+	fmt.Printf("sha256: %s\n", movie.RomHash(rom))
+	if free := chip8.DefaultMemorySize - 0x200 - len(rom); free < 0 {
+		fmt.Printf("the rom is %d bytes too large for %d bytes of memory\n", -free, chip8.DefaultMemorySize)
+	}
+
+	counts := map[string]int{}
+	for i := 0; i+1 < len(rom); i += 2 {
+		opcode := uint16(rom[i])<<8 | uint16(rom[i+1])
+		counts[strings.Fields(chip8.Disassemble(opcode))[0]]++
+	}
+
+	mnemonics := make([]string, 0, len(counts))
+	for m := range counts {
+		mnemonics = append(mnemonics, m)
+	}
+	sort.Slice(mnemonics, func(i, j int) bool {
+		if counts[mnemonics[i]] != counts[mnemonics[j]] {
+			return counts[mnemonics[i]] > counts[mnemonics[j]]
+		}
+		return mnemonics[i] < mnemonics[j]
+	})
+
+	fmt.Println()
+	for _, m := range mnemonics {
+		fmt.Printf("%-5s %5d\n", m, counts[m])
+	}
+	return 0
+}
@@ -0,0 +1,18 @@
+package input
+
+import (
+	"chip8/src/chip8"
+)
+
+// Composite merges several keyboards into one. A key is down when it is
+// down on any of them.
+type Composite []chip8.Keyboard
+
+func (c Composite) IsDown(key uint8) bool {
+	for _, k := range c {
+		if k.IsDown(key) {
+			return true
+		}
+	}
+	return false
+}
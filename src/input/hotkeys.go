@@ -0,0 +1,183 @@
+package input
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Action is an emulator function that can be bound to a hotkey
+type Action string
+
+const (
+	Pause        Action = "pause"
+	FrameAdvance Action = "frame-advance"
+	Reset        Action = "reset"
+	// FastForward toggles fast forward, FastForwardHold only lasts while the key is down
+	FastForward     Action = "fast-forward"
+	FastForwardHold Action = "fast-forward-hold"
+	Screenshot      Action = "screenshot"
+	ShowStats       Action = "show-stats"
+)
+
+// StateSlots is the number of save state slots
+const StateSlots = 9
+
+// SaveState returns the action that saves into slot, counting from 1
+func SaveState(slot int) Action {
+	return Action("save-" + strconv.Itoa(slot))
+}
+
+// LoadState returns the action that loads from slot, counting from 1
+func LoadState(slot int) Action {
+	return Action("load-" + strconv.Itoa(slot))
+}
+
+// StateSlot returns the slot of a SaveState or LoadState action and whether it saves
+func (a Action) StateSlot() (slot int, save bool, ok bool) {
+	for i := 1; i <= StateSlots; i++ {
+		switch a {
+		case SaveState(i):
+			return i, true, true
+		case LoadState(i):
+			return i, false, true
+		}
+	}
+	return 0, false, false
+}
+
+// Actions returns every action that can be bound
+func Actions() []Action {
+	actions := []Action{Pause, FrameAdvance, Reset, FastForward, FastForwardHold, Screenshot, ShowStats}
+	for i := 1; i <= StateSlots; i++ {
+		actions = append(actions, SaveState(i), LoadState(i))
+	}
+	return actions
+}
+
+// Hotkeys binds actions to key names. Names are the ones of Keymap and may
+// start with modifiers, as in "Shift+F1" or "Ctrl+R".
+type Hotkeys map[Action]string
+
+// DefaultHotkeys avoids the keys of all keymap presets. Function keys load
+// states and together with Shift save them.
+func DefaultHotkeys() Hotkeys {
+	h := Hotkeys{
+		Pause:           "P",
+		FrameAdvance:    "N",
+		Reset:           "F10",
+		FastForward:     "T",
+		FastForwardHold: "Tab",
+		Screenshot:      "F12",
+		ShowStats:       "I",
+	}
+	for i := 1; i <= StateSlots; i++ {
+		h[SaveState(i)] = "Shift+F" + strconv.Itoa(i)
+		h[LoadState(i)] = "F" + strconv.Itoa(i)
+	}
+	return h
+}
+
+// ReadHotkeys parses a hotkey file. Every line binds an action to a key and
+// replaces its default binding, "none" leaves the action unbound:
+//
+//	pause = Space
+//	show-stats = none
+func ReadHotkeys(r io.Reader) (Hotkeys, error) {
+	h := DefaultHotkeys()
+	known := map[Action]bool{}
+	for _, a := range Actions() {
+		known[a] = true
+	}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		i := strings.Index(text, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("line %d: expected \"action = key\", got %q", line, text)
+		}
+		action := Action(strings.ToLower(strings.TrimSpace(text[:i])))
+		if !known[action] {
+			return nil, fmt.Errorf("line %d: unknown action %q", line, action)
+		}
+		key := strings.TrimSpace(text[i+1:])
+		if key == "" || strings.EqualFold(key, "none") {
+			delete(h, action)
+		} else {
+			h[action] = key
+		}
+	}
+
+	return h, scanner.Err()
+}
+
+// Check reports hotkeys that are bound twice or that press a key of keymap.
+// Hotkeys with modifiers may use keypad keys, the modifier tells them apart.
+func (h Hotkeys) Check(keymap Keymap) error {
+	actions := make([]string, 0, len(h))
+	for a := range h {
+		actions = append(actions, string(a))
+	}
+	sort.Strings(actions)
+
+	used := map[Chord]Action{}
+	for _, a := range actions {
+		name := h[Action(a)]
+		c := ParseChord(name)
+		c.Key = strings.ToLower(c.Key)
+		if other, ok := used[c]; ok {
+			return fmt.Errorf("hotkey %s is bound to both %s and %s", name, other, a)
+		}
+		used[c] = Action(a)
+
+		if c.Shift || c.Ctrl || c.Alt {
+			continue
+		}
+		for keyName, key := range keymap {
+			if strings.EqualFold(keyName, c.Key) {
+				return fmt.Errorf("hotkey %s for %s is already CHIP-8 key %X", name, a, key)
+			}
+		}
+	}
+	return nil
+}
+
+// Chord is a key name together with the modifiers that have to be held
+type Chord struct {
+	Key   string
+	Shift bool
+	Ctrl  bool
+	Alt   bool
+}
+
+// ParseChord splits the modifiers off a hotkey name such as "Ctrl+Shift+S"
+func ParseChord(name string) Chord {
+	c := Chord{Key: name}
+	for {
+		i := strings.Index(c.Key, "+")
+		if i < 1 || i == len(c.Key)-1 {
+			return c
+		}
+		switch strings.ToLower(c.Key[:i]) {
+		case "shift":
+			c.Shift = true
+		case "ctrl", "control":
+			c.Ctrl = true
+		case "alt":
+			c.Alt = true
+		default:
+			return c
+		}
+		c.Key = c.Key[i+1:]
+	}
+}
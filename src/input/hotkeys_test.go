@@ -0,0 +1,83 @@
+package input
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadHotkeys(t *testing.T) {
+	h, err := ReadHotkeys(strings.NewReader(`
+# comment
+pause = Space
+show-stats = none
+LOAD-3 = Ctrl+3
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h[Pause] != "Space" || h[LoadState(3)] != "Ctrl+3" || h[Reset] != DefaultHotkeys()[Reset] {
+		t.Errorf("bindings %v", h)
+	}
+	if _, ok := h[ShowStats]; ok {
+		t.Error("show-stats is still bound")
+	}
+
+	for _, text := range []string{"pause", "rewind = R", "= P"} {
+		if _, err := ReadHotkeys(strings.NewReader(text)); err == nil {
+			t.Errorf("ReadHotkeys(%q) succeeded", text)
+		}
+	}
+}
+
+func TestHotkeys_Check(t *testing.T) {
+	for name := range Presets {
+		keymap, _ := Preset(name)
+		if err := DefaultHotkeys().Check(keymap); err != nil {
+			t.Errorf("default hotkeys collide with %s: %s", name, err)
+		}
+	}
+
+	qwerty, _ := Preset("qwerty")
+	for _, h := range []Hotkeys{
+		{Pause: "q"},
+		{Pause: "P", Reset: "p"},
+		{Pause: "Shift+F1", Reset: "shift+F1"},
+	} {
+		if err := h.Check(qwerty); err == nil {
+			t.Errorf("%v passed the check", h)
+		}
+	}
+
+	if err := (Hotkeys{Pause: "Ctrl+Q"}).Check(qwerty); err != nil {
+		t.Errorf("a keypad key with a modifier was rejected: %s", err)
+	}
+}
+
+func TestParseChord(t *testing.T) {
+	for name, want := range map[string]Chord{
+		"F1":           {Key: "F1"},
+		"Shift+F1":     {Key: "F1", Shift: true},
+		"ctrl+alt+S":   {Key: "S", Ctrl: true, Alt: true},
+		"+":            {Key: "+"},
+		"Shift++":      {Key: "+", Shift: true},
+		"Keypad +":     {Key: "Keypad +"},
+		"Alt+Keypad +": {Key: "Keypad +", Alt: true},
+	} {
+		if got := ParseChord(name); got != want {
+			t.Errorf("ParseChord(%q) = %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestAction_StateSlot(t *testing.T) {
+	if slot, save, ok := SaveState(4).StateSlot(); slot != 4 || !save || !ok {
+		t.Errorf("SaveState(4).StateSlot() = %d, %t, %t", slot, save, ok)
+	}
+	if slot, save, ok := LoadState(9).StateSlot(); slot != 9 || save || !ok {
+		t.Errorf("LoadState(9).StateSlot() = %d, %t, %t", slot, save, ok)
+	}
+	if _, _, ok := Pause.StateSlot(); ok {
+		t.Error("pause has a state slot")
+	}
+}
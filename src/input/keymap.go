@@ -0,0 +1,130 @@
+package input
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Keymap binds host key names to CHIP-8 keys. Names are the ones SDL uses,
+// such as "Q", "Keypad 7" or "&", and are compared without regard to case.
+type Keymap map[string]uint8
+
+// Presets are the built in keymaps. All of them lay the keypad out as on the COSMAC VIP:
+//
+//	1 2 3 C
+//	4 5 6 D
+//	7 8 9 E
+//	A 0 B F
+var Presets = map[string]Keymap{
+	"qwerty": {
+		"1": 0x1, "2": 0x2, "3": 0x3, "4": 0xC,
+		"Q": 0x4, "W": 0x5, "E": 0x6, "R": 0xD,
+		"A": 0x7, "S": 0x8, "D": 0x9, "F": 0xE,
+		"Z": 0xA, "X": 0x0, "C": 0xB, "V": 0xF,
+	},
+	"azerty": {
+		"&": 0x1, "é": 0x2, "\"": 0x3, "'": 0xC,
+		"A": 0x4, "Z": 0x5, "E": 0x6, "R": 0xD,
+		"Q": 0x7, "S": 0x8, "D": 0x9, "F": 0xE,
+		"W": 0xA, "X": 0x0, "C": 0xB, "V": 0xF,
+	},
+	"numpad": {
+		"Keypad 7": 0x1, "Keypad 8": 0x2, "Keypad 9": 0x3, "Keypad /": 0xC,
+		"Keypad 4": 0x4, "Keypad 5": 0x5, "Keypad 6": 0x6, "Keypad *": 0xD,
+		"Keypad 1": 0x7, "Keypad 2": 0x8, "Keypad 3": 0x9, "Keypad -": 0xE,
+		"Keypad 0": 0xA, "Keypad .": 0x0, "Keypad Enter": 0xB, "Keypad +": 0xF,
+	},
+}
+
+// DefaultPreset is used when no keymap is configured
+const DefaultPreset = "qwerty"
+
+// Preset returns a copy of a built in keymap
+func Preset(name string) (Keymap, bool) {
+	p, ok := Presets[strings.ToLower(name)]
+	if !ok {
+		return nil, false
+	}
+	return p.Copy(), true
+}
+
+func (k Keymap) Copy() Keymap {
+	c := Keymap{}
+	for name, key := range k {
+		c[name] = key
+	}
+	return c
+}
+
+// ReadKeymap parses a keymap file. Every line binds a key name to a CHIP-8
+// key, and an optional "preset" line starts from a built in keymap:
+//
+//	# start from the numpad layout and add arrow keys
+//	preset numpad
+//	Up = 2
+//	Down = 8
+func ReadKeymap(r io.Reader) (Keymap, error) {
+	return readBindings(r, Presets)
+}
+
+func readBindings(r io.Reader, presets map[string]Keymap) (Keymap, error) {
+	k := Keymap{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(text, "preset ") {
+			p, ok := presets[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(text, "preset ")))]
+			if !ok {
+				return nil, fmt.Errorf("line %d: unknown preset %q", line, text)
+			}
+			for name, key := range p {
+				k[name] = key
+			}
+			continue
+		}
+
+		i := strings.LastIndex(text, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("line %d: expected \"name = key\", got %q", line, text)
+		}
+		name := strings.TrimSpace(text[:i])
+		key, err := strconv.ParseUint(strings.TrimSpace(text[i+1:]), 16, 4)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid CHIP-8 key in %q", line, text)
+		}
+		k[name] = uint8(key)
+	}
+
+	return k, scanner.Err()
+}
+
+// Write stores the keymap in the format read by ReadKeymap, ordered by CHIP-8 key
+func (k Keymap) Write(w io.Writer) error {
+	names := make([]string, 0, len(k))
+	for name := range k {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if k[names[i]] != k[names[j]] {
+			return k[names[i]] < k[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		if _, err := fmt.Fprintf(w, "%s = %X\n", name, k[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
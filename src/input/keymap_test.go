@@ -0,0 +1,75 @@
+package input
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadKeymap(t *testing.T) {
+	k, err := ReadKeymap(strings.NewReader(`
+# arrows on top of the numpad
+preset numpad
+Up = 2
+Keypad 5 = f
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if k["Up"] != 0x2 || k["Keypad 5"] != 0xF || k["Keypad 7"] != 0x1 {
+		t.Errorf("Unexpected keymap %v", k)
+	}
+
+	for _, bad := range []string{"Q", "Q = 10", "preset dvorak", "= 1"} {
+		if _, err := ReadKeymap(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestKeymap_WriteRoundTrip(t *testing.T) {
+	for name, preset := range Presets {
+		out := bytes.Buffer{}
+		if err := preset.Write(&out); err != nil {
+			t.Fatal(err)
+		}
+
+		k, err := ReadKeymap(&out)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if !reflect.DeepEqual(k, preset) {
+			t.Errorf("%s changed after a round trip: %v", name, k)
+		}
+	}
+}
+
+func TestPresets_CoverKeypad(t *testing.T) {
+	for name, preset := range Presets {
+		seen := map[uint8]bool{}
+		for _, key := range preset {
+			seen[key] = true
+		}
+		if len(seen) != 16 || len(preset) != 16 {
+			t.Errorf("Expected %s to bind every key exactly once", name)
+		}
+	}
+}
+
+func TestKeypad(t *testing.T) {
+	k := Keypad{}
+	k.Press(0xA)
+	k.Press(0x1)
+	k.Release(0x1)
+
+	if !k.IsDown(0xA) || k.IsDown(0x1) || k.Mask() != 1<<0xA {
+		t.Errorf("Unexpected keypad state %016b", k.Mask())
+	}
+
+	k.ReleaseAll()
+	if k.Mask() != 0 {
+		t.Errorf("Expected every key to be released")
+	}
+}
@@ -0,0 +1,29 @@
+package input
+
+// Keypad holds the pressed state of the 16 CHIP-8 keys. Frontends update it
+// from their input events and the cpu reads it through IsDown.
+type Keypad struct {
+	keys uint16
+}
+
+func (k *Keypad) Press(key uint8) {
+	k.keys |= 1 << (key & 0x0F)
+}
+
+func (k *Keypad) Release(key uint8) {
+	k.keys &^= 1 << (key & 0x0F)
+}
+
+// ReleaseAll is used when the window loses focus and release events may go missing
+func (k *Keypad) ReleaseAll() {
+	k.keys = 0
+}
+
+func (k *Keypad) IsDown(key uint8) bool {
+	return key < 0x10 && k.keys&(1<<key) != 0
+}
+
+// Mask returns one bit per key, bit n is key n
+func (k *Keypad) Mask() uint16 {
+	return k.keys
+}
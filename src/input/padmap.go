@@ -0,0 +1,95 @@
+package input
+
+import (
+	"io"
+	"strings"
+)
+
+// PadPresets are the built in gamepad mappings. Button names are the ones
+// SDL uses for game controllers, axes get a "+" or "-" suffix for their
+// direction. Plain joysticks have no such names and are not supported.
+var PadPresets = map[string]Keymap{
+	"default": {
+		"dpup": 0x2, "dpdown": 0x8, "dpleft": 0x4, "dpright": 0x6,
+		"lefty-": 0x2, "lefty+": 0x8, "leftx-": 0x4, "leftx+": 0x6,
+		"a": 0x5, "b": 0xA, "x": 0xB, "y": 0x0,
+		"back": 0xE, "start": 0xF,
+	},
+}
+
+// DefaultPadPreset is used when no gamepad mapping is configured
+const DefaultPadPreset = "default"
+
+// PadPreset returns a copy of a built in gamepad mapping
+func PadPreset(name string) (Keymap, bool) {
+	p, ok := PadPresets[strings.ToLower(name)]
+	if !ok {
+		return nil, false
+	}
+	return p.Copy(), true
+}
+
+// ReadPadmap parses a gamepad mapping file. It has the same format as a
+// keymap file, but presets refer to PadPresets:
+//
+//	preset default
+//	a = 4
+//	righttrigger+ = 6
+func ReadPadmap(r io.Reader) (Keymap, error) {
+	return readBindings(r, PadPresets)
+}
+
+type heldInput struct {
+	source int
+	name   string
+}
+
+// Mapper tracks which named inputs of which sources are held and turns them
+// into CHIP-8 keys. A key stays down as long as any input bound to it is held.
+type Mapper struct {
+	keymap Keymap
+	held   map[heldInput]uint8
+}
+
+func NewMapper(keymap Keymap) *Mapper {
+	m := &Mapper{keymap: Keymap{}, held: map[heldInput]uint8{}}
+	for name, key := range keymap {
+		m.keymap[strings.ToLower(name)] = key
+	}
+	return m
+}
+
+// Set records whether the input called name on source is held. Inputs that
+// are not bound are ignored.
+func (m *Mapper) Set(source int, name string, down bool) {
+	in := heldInput{source: source, name: strings.ToLower(name)}
+	key, bound := m.keymap[in.name]
+	if down && bound {
+		m.held[in] = key
+	} else {
+		delete(m.held, in)
+	}
+}
+
+// ReleaseSource lets go of everything held on source, for example when a controller is unplugged
+func (m *Mapper) ReleaseSource(source int) {
+	for in := range m.held {
+		if in.source == source {
+			delete(m.held, in)
+		}
+	}
+}
+
+// ReleaseAll releases the inputs of every source
+func (m *Mapper) ReleaseAll() {
+	m.held = map[heldInput]uint8{}
+}
+
+func (m *Mapper) IsDown(key uint8) bool {
+	for _, k := range m.held {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package input
+
+import (
+	"chip8/src/chip8"
+	"strings"
+	"testing"
+)
+
+func TestReadPadmap(t *testing.T) {
+	k, err := ReadPadmap(strings.NewReader("preset default\na = 4\nrighttrigger+ = 6\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if k["a"] != 0x4 || k["righttrigger+"] != 0x6 || k["dpup"] != 0x2 {
+		t.Errorf("Unexpected padmap %v", k)
+	}
+
+	if _, err := ReadPadmap(strings.NewReader("preset qwerty")); err == nil {
+		t.Errorf("Expected keyboard presets to be unknown in padmaps")
+	}
+}
+
+func TestMapper(t *testing.T) {
+	m := NewMapper(Keymap{"DPUP": 0x2, "lefty-": 0x2, "a": 0x5})
+
+	m.Set(0, "dpup", true)
+	m.Set(1, "lefty-", true)
+	m.Set(0, "start", true)
+	if !m.IsDown(0x2) {
+		t.Errorf("Expected key 2 to be down")
+	}
+
+	m.Set(0, "dpup", false)
+	if !m.IsDown(0x2) {
+		t.Errorf("Expected key 2 to stay down while the stick on the second pad holds it")
+	}
+
+	m.ReleaseSource(1)
+	if m.IsDown(0x2) {
+		t.Errorf("Expected key 2 to be released when the second pad is unplugged")
+	}
+
+	m.Set(0, "a", true)
+	m.ReleaseAll()
+	if m.IsDown(0x5) {
+		t.Errorf("Expected ReleaseAll to release key 5")
+	}
+}
+
+func TestComposite(t *testing.T) {
+	c := Composite{&chip8.TestKeyboard{KeysDown: []uint8{0x1}}, &chip8.TestKeyboard{KeysDown: []uint8{0xB}}}
+
+	if !c.IsDown(0x1) || !c.IsDown(0xB) || c.IsDown(0x2) {
+		t.Errorf("Expected exactly keys 1 and B to be down")
+	}
+}
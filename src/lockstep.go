@@ -0,0 +1,57 @@
+package main
+
+import (
+	"chip8/src/headless"
+	"chip8/src/lockstep"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// runLockstep implements `chip8 lockstep rom.ch8`. Every profile runs with
+// the same key script and random seed. It returns 0 when all profiles agree,
+// 1 when they diverge and 2 on errors.
+func runLockstep(args []string) int {
+	flags := flag.NewFlagSet("lockstep", flag.ExitOnError)
+	profiles := flags.String("profiles", "default,cosmac,schip", "comma separated quirk profiles to compare")
+	steps := flags.Uint64("steps", 1000000, "maximum number of instructions to execute")
+	ipf := flags.Int("ipf", 10, "instructions per frame")
+	keys := flags.String("keys", "", "scripted key presses by frame, e.g. 30:5,32:,60:4a")
+	seed := flags.Uint64("seed", 1, "random number generator seed shared by all profiles")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: chip8 lockstep [-profiles a,b] [-steps n] [-ipf n] [-keys script] [-seed n] rom.ch8")
+		return 2
+	}
+	events, err := headless.ParseKeyScript(*keys)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	rom, err := ioutil.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	r, err := lockstep.NewRunner(rom, strings.Split(*profiles, ","), events, *seed)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	r.InstructionsPerFrame = *ipf
+
+	d := r.Run(*steps)
+	if d == nil {
+		fmt.Printf("No divergence within %d instructions\n", *steps)
+		return 0
+	}
+
+	fmt.Println(d)
+	r.WriteStates(os.Stdout)
+	return 1
+}
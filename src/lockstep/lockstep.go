@@ -0,0 +1,134 @@
+package lockstep
+
+import (
+	"bytes"
+	"chip8/src/chip8"
+	"chip8/src/headless"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Instance is a single cpu taking part in a lockstep run
+type Instance struct {
+	Name    string
+	Cpu     *chip8.Cpu
+	Display *chip8.Framebuffer
+}
+
+// Divergence describes the first instruction after which the instances disagreed
+type Divergence struct {
+	// Step is the 1-based number of the instruction that caused the divergence
+	Step   uint64
+	PC     uint16
+	Opcode uint16
+	// Differences maps the name of every instance that disagrees with the
+	// first instance to the fields that differ, "screen" for the pixels
+	Differences map[string][]string
+}
+
+func (d Divergence) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Divergence after instruction %d: %#04x at PC %#04x", d.Step, d.Opcode, d.PC)
+	names := make([]string, 0, len(d.Differences))
+	for name := range d.Differences {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n  %s: %s", name, strings.Join(d.Differences[name], ", "))
+	}
+	return b.String()
+}
+
+// Runner steps several cpus together and stops as soon as their states differ
+type Runner struct {
+	Instances []Instance
+	// Keyboard is shared by all instances and advanced at every frame
+	Keyboard *headless.ScriptedKeyboard
+	// InstructionsPerFrame controls how often the timers are decremented
+	InstructionsPerFrame int
+
+	// steps is the number of instructions run by earlier calls to Run
+	steps uint64
+}
+
+// NewRunner creates one cpu per quirk profile, all loaded with the same rom,
+// pressing the keys of the same script and drawing random numbers from
+// generators with the same seed.
+func NewRunner(rom []byte, profiles []string, keys []headless.KeyEvent, seed uint64) (*Runner, error) {
+	if len(profiles) < 2 {
+		return nil, fmt.Errorf("at least two profiles are needed, got %d", len(profiles))
+	}
+
+	r := &Runner{Keyboard: &headless.ScriptedKeyboard{Events: keys}, InstructionsPerFrame: 10}
+	for _, name := range profiles {
+		quirks, ok := chip8.QuirkProfile(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown quirk profile %q", name)
+		}
+
+		display := &chip8.Framebuffer{}
+		cpu := chip8.NewCPU(chip8.DefaultMemorySize, display, r.Keyboard)
+		cpu.Quirks = quirks
+		cpu.SetRng(chip8.NewSeededRng(seed))
+		if err := cpu.LoadProgram(bytes.NewReader(rom)); err != nil {
+			return nil, err
+		}
+		r.Instances = append(r.Instances, Instance{Name: name, Cpu: &cpu, Display: display})
+	}
+
+	return r, nil
+}
+
+// Run steps every instance at most maxSteps times, continuing where the last
+// call stopped. It returns nil if no divergence was found.
+func (r *Runner) Run(maxSteps uint64) *Divergence {
+	first := r.Instances[0].Cpu
+	for end := r.steps + maxSteps; r.steps < end; {
+		if int(first.PC)+1 >= len(first.Memory) {
+			return nil
+		}
+		r.steps++
+		step := r.steps
+		pc := first.PC
+		opcode := uint16(first.Memory[pc])<<8 | uint16(first.Memory[pc+1])
+		if r.InstructionsPerFrame > 0 && (step-1)%uint64(r.InstructionsPerFrame) == 0 {
+			r.Keyboard.SetFrame((step - 1) / uint64(r.InstructionsPerFrame))
+		}
+
+		for _, instance := range r.Instances {
+			instance.Cpu.Step()
+			if r.InstructionsPerFrame > 0 && step%uint64(r.InstructionsPerFrame) == 0 {
+				instance.Cpu.DecrementTimers()
+			}
+		}
+
+		differences := map[string][]string{}
+		for _, instance := range r.Instances[1:] {
+			fields := chip8.CompareState(first, instance.Cpu)
+			if instance.Display.Pixels != r.Instances[0].Display.Pixels {
+				fields = append(fields, "screen")
+			}
+			if len(fields) > 0 {
+				differences[instance.Name] = fields
+			}
+		}
+
+		if len(differences) > 0 {
+			return &Divergence{Step: step, PC: pc, Opcode: opcode, Differences: differences}
+		}
+	}
+
+	return nil
+}
+
+// WriteStates prints the registers of every instance side by side
+func (r *Runner) WriteStates(w io.Writer) {
+	for _, instance := range r.Instances {
+		c := instance.Cpu
+		fmt.Fprintf(w, "%-10s PC=%04x I=%04x SP=%02x DT=%02x ST=%02x V=% x\n",
+			instance.Name, c.PC, c.I, c.SP, c.DT, c.ST, c.V[:])
+	}
+}
@@ -0,0 +1,118 @@
+package lockstep
+
+import (
+	"chip8/src/headless"
+	"reflect"
+	"testing"
+)
+
+func TestRunner_FindsQuirk(t *testing.T) {
+	rom := []byte{
+		0x60, 0x03, // LD V0, 3
+		0x61, 0x04, // LD V1, 4
+		0x80, 0x16, // SHR V0, V1
+		0x12, 0x06, // JP 0x206
+	}
+
+	r, err := NewRunner(rom, []string{"default", "cosmac"}, nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := r.Run(100)
+	if d == nil {
+		t.Fatalf("Expected the profiles to diverge")
+	}
+
+	if d.Step != 3 || d.Opcode != 0x8016 || d.PC != 0x204 {
+		t.Errorf("Expected divergence at step 3 on 0x8016 at 0x204, got %s", d)
+	}
+
+	if fields := d.Differences["cosmac"]; len(fields) != 2 || fields[0] != "V0" || fields[1] != "VF" {
+		t.Errorf("Expected V0 and VF to differ, got %v", fields)
+	}
+}
+
+func TestRunner_NoDivergence(t *testing.T) {
+	rom := []byte{0x60, 0x03, 0x70, 0x01, 0x12, 0x02}
+
+	r, err := NewRunner(rom, []string{"default", "schip"}, nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := r.Run(1000); d != nil {
+		t.Errorf("Expected no divergence, got %s", d)
+	}
+}
+
+func TestRunner_SameRandomNumbers(t *testing.T) {
+	rom := []byte{
+		0xC0, 0xFF, // RND V0, 0xFF
+		0x12, 0x00, // JP 0x200
+	}
+
+	r, err := NewRunner(rom, []string{"default", "schip"}, nil, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := r.Run(1000); d != nil {
+		t.Errorf("Expected the same random numbers for every profile, got %s", d)
+	}
+}
+
+func TestRunner_Keys(t *testing.T) {
+	rom := []byte{
+		0x60, 0x05, // LD V0, 5
+		0xE0, 0x9E, // SKP V0
+		0x12, 0x02, // JP 0x202
+		0x61, 0x01, // LD V1, 1
+		0x12, 0x08, // JP 0x208
+	}
+	keys, err := headless.ParseKeyScript("3:5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewRunner(rom, []string{"default", "cosmac"}, keys, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := r.Run(30); d != nil || r.Instances[0].Cpu.V[1] != 0 {
+		t.Fatalf("Expected the rom to wait for key 5 until frame 3, got %v and V1 %d", d, r.Instances[0].Cpu.V[1])
+	}
+	if d := r.Run(10); d != nil {
+		t.Fatalf("Expected no divergence, got %s", d)
+	}
+	for _, instance := range r.Instances {
+		if instance.Cpu.V[1] != 1 {
+			t.Errorf("%s: expected key 5 to be pressed in frame 3", instance.Name)
+		}
+	}
+}
+
+func TestRunner_ScreenDivergence(t *testing.T) {
+	rom := []byte{0x60, 0x03, 0x12, 0x02}
+
+	r, err := NewRunner(rom, []string{"default", "schip"}, nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Instances[1].Display.SetPixel(0, 0, true)
+
+	d := r.Run(1)
+	if d == nil {
+		t.Fatalf("Expected the screens to diverge")
+	}
+	if fields := d.Differences["schip"]; !reflect.DeepEqual(fields, []string{"screen"}) {
+		t.Errorf("Expected only the screen to differ, got %v", fields)
+	}
+}
+
+func TestNewRunner_UnknownProfile(t *testing.T) {
+	if _, err := NewRunner(nil, []string{"default", "nope"}, nil, 1); err == nil {
+		t.Errorf("Expected an error for an unknown profile")
+	}
+}
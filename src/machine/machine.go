@@ -0,0 +1,445 @@
+package machine
+
+import (
+	"bytes"
+	"chip8/src/chip8"
+	"chip8/src/headless"
+	"context"
+	"sync"
+	"time"
+)
+
+// FrameRate is the number of frames per second at normal speed, the timers
+// tick once per frame
+const FrameRate = 60
+
+// StatsInterval is how often the rates of Stats are measured
+const StatsInterval = time.Second
+
+// Frontend is the window or terminal that shows the screen and reads input
+type Frontend interface {
+	chip8.Display
+	PumpEvents()
+	QuitRequested() bool
+	// Paused reports whether the frontend cannot be used right now, for
+	// example because the window lost focus
+	Paused() bool
+}
+
+// Machine runs a cpu in real time. While Run is running, the machine may
+// only be changed through Post, Do and the methods built on them, which are
+// safe to call from any goroutine.
+type Machine struct {
+	Cpu      *chip8.Cpu
+	Frontend Frontend
+	Keyboard headless.FrameKeyboard
+	// InstructionsPerFrame is the number of instructions between timer ticks
+	InstructionsPerFrame int
+	// Frames stops Run after this many frames if it is not 0
+	Frames uint64
+	// OnFrame is called after every frame if it is set
+	OnFrame func()
+	// OnPaused is called instead while paused, before the frontend renders
+	// the unchanged screen, so overlays can still be updated
+	OnPaused func()
+	// SkipIdle lets frames skip idle loops up to the next timer tick, see
+	// chip8.Cpu.SkipIdle
+	SkipIdle bool
+	// RunAhead is the number of frames shown ahead of the emulated state to
+	// hide input lag, see runAhead
+	RunAhead int
+
+	Frame uint64
+
+	rom    []byte
+	step   int
+	speed  float64
+	paused bool
+	halted bool
+	// ahead is set while frames are run that are undone again
+	ahead bool
+
+	// instructions and frames count what was run, including frames that
+	// were undone by Restore but not those run ahead
+	instructions uint64
+	frames       uint64
+	stats        Stats
+	window       statsWindow
+
+	// commands are run in order by Run, wake tells it that there are new ones
+	lock     sync.Mutex
+	commands []func(m *Machine)
+	wake     chan struct{}
+}
+
+// Snapshot is the state of a machine that can be restored later
+type Snapshot struct {
+	Cpu    chip8.State
+	Pixels [chip8.ScreenWidth * chip8.ScreenHeight]bool
+	Frame  uint64
+	// Step is the number of instructions already run in Frame
+	Step int
+}
+
+// Stats describes how fast a machine runs and the state of its timers
+type Stats struct {
+	Frame        uint64
+	Instructions uint64
+	// The rates are those of the last StatsInterval
+	InstructionsPerSecond float64
+	FramesPerSecond       float64
+	// FrameTime is the average time taken to run and show a frame
+	FrameTime time.Duration
+	DT, ST    uint8
+	// WaitingForKey is set while the cpu waits for a key press on Fx0A
+	WaitingForKey bool
+}
+
+// statsWindow holds the counters at the start of the current StatsInterval
+type statsWindow struct {
+	start        time.Time
+	instructions uint64
+	frames       uint64
+	// busy is the time spent running frames since start
+	busy time.Duration
+}
+
+// New creates a machine with memorySize bytes of memory and loads rom into it
+func New(memorySize int16, rom []byte, frontend Frontend, keyboard headless.FrameKeyboard) (*Machine, error) {
+	cpu := chip8.NewCPU(memorySize, frontend, keyboard)
+	if err := cpu.LoadProgram(bytes.NewReader(rom)); err != nil {
+		return nil, err
+	}
+
+	return &Machine{
+		Cpu:                  &cpu,
+		Frontend:             frontend,
+		Keyboard:             keyboard,
+		InstructionsPerFrame: 10,
+		SkipIdle:             true,
+		rom:                  rom,
+		speed:                1,
+		wake:                 make(chan struct{}, 1),
+	}, nil
+}
+
+// Run runs frames at the current speed until the frontend asks to quit, the
+// program counter leaves memory, Frames have been run or ctx is cancelled.
+// Only the last case returns an error.
+func (m *Machine) Run(ctx context.Context) error {
+	next := time.Now()
+	m.startWindow(next)
+	for m.Frames == 0 || m.Frame < m.Frames {
+		m.Frontend.PumpEvents()
+		m.runCommands()
+		if m.Frontend.QuitRequested() || m.halted {
+			return nil
+		}
+
+		if !m.paused && !m.Frontend.Paused() {
+			started := time.Now()
+			m.runFrame()
+			m.window.busy += time.Since(started)
+		} else {
+			// Notifications and the status still change while paused
+			if m.OnPaused != nil {
+				m.OnPaused()
+			}
+			m.Frontend.Render()
+		}
+		m.measure(time.Now())
+
+		// A paused machine still handles events at the normal frame rate
+		period := m.period()
+		if m.paused && period < time.Second/FrameRate {
+			period = time.Second / FrameRate
+		}
+		next = next.Add(period)
+		if now := time.Now(); next.Before(now) {
+			// Running behind, for example after a pause, must not make the
+			// following frames run faster to catch up
+			next = now
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		for waiting := true; waiting; {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-m.wake:
+				m.runCommands()
+			case <-timer.C:
+				waiting = false
+			}
+		}
+	}
+	return nil
+}
+
+// Post queues f to run on the goroutine of Run between two frames and
+// returns at once. Queued functions run in the order they were posted.
+func (m *Machine) Post(f func(m *Machine)) {
+	m.lock.Lock()
+	m.commands = append(m.commands, f)
+	m.lock.Unlock()
+
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Do posts f and waits until it has run. It blocks until Run is running and
+// must not be called from inside Run, for example from OnFrame or a posted
+// function.
+func (m *Machine) Do(f func(m *Machine)) {
+	done := make(chan struct{})
+	m.Post(func(m *Machine) {
+		f(m)
+		close(done)
+	})
+	<-done
+}
+
+// Pause stops running frames until Resume is called, single steps still work
+func (m *Machine) Pause() {
+	m.Post(func(m *Machine) { m.paused = true })
+}
+
+func (m *Machine) Resume() {
+	m.Post(func(m *Machine) { m.paused = false })
+}
+
+// TogglePause pauses a running machine and resumes a paused one
+func (m *Machine) TogglePause() {
+	m.Post(func(m *Machine) { m.paused = !m.paused })
+}
+
+// Reset restarts the program, a hard reset also loads the rom again and so
+// undoes any changes the program made to memory
+func (m *Machine) Reset(hard bool) {
+	m.Post(func(m *Machine) { m.reset(hard) })
+}
+
+// StepInstruction runs a single instruction
+func (m *Machine) StepInstruction() {
+	m.Post(func(m *Machine) {
+		m.stepInstruction()
+		m.Frontend.Render()
+	})
+}
+
+// StepFrame runs instructions until the current frame is finished
+func (m *Machine) StepFrame() {
+	m.Post(func(m *Machine) { m.runFrame() })
+}
+
+// SetSpeed changes how fast frames are run, 2 is twice and 0.5 half the
+// normal speed. A speed of 0 or less runs frames as fast as possible.
+func (m *Machine) SetSpeed(speed float64) {
+	m.Post(func(m *Machine) { m.speed = speed })
+}
+
+// The following methods may only be called from the goroutine of Run, that
+// is from posted functions, OnFrame or the frontend
+
+// IsPaused reports whether Pause was called, it ignores whether the frontend is paused
+func (m *Machine) IsPaused() bool {
+	return m.paused
+}
+
+func (m *Machine) Speed() float64 {
+	return m.speed
+}
+
+// Stats returns the statistics of the machine
+func (m *Machine) Stats() Stats {
+	s := m.stats
+	s.Frame = m.Frame
+	s.Instructions = m.instructions
+	s.DT, s.ST = m.Cpu.DT, m.Cpu.ST
+	s.WaitingForKey = m.Cpu.WaitingForKey()
+	return s
+}
+
+// Snapshot copies the state of the cpu and the screen
+func (m *Machine) Snapshot() Snapshot {
+	s := Snapshot{Cpu: m.Cpu.Snapshot(), Frame: m.Frame, Step: m.step}
+	for y := 0; y < chip8.ScreenHeight; y++ {
+		for x := 0; x < chip8.ScreenWidth; x++ {
+			s.Pixels[y*chip8.ScreenWidth+x] = m.Frontend.GetPixel(uint8(x), uint8(y))
+		}
+	}
+	return s
+}
+
+// Restore returns to a snapshot taken by Snapshot
+func (m *Machine) Restore(s Snapshot) error {
+	if err := m.restore(s); err != nil {
+		return err
+	}
+	m.Frontend.Render()
+	return nil
+}
+
+func (m *Machine) restore(s Snapshot) error {
+	if err := m.Cpu.Restore(s.Cpu); err != nil {
+		return err
+	}
+
+	m.Frontend.Clear()
+	for i, on := range s.Pixels {
+		if on {
+			m.Frontend.SetPixel(uint8(i%chip8.ScreenWidth), uint8(i/chip8.ScreenWidth), true)
+		}
+	}
+	m.Frame = s.Frame
+	m.step = s.Step
+	m.halted = false
+	return nil
+}
+
+// runCommands runs the posted functions, including those they post themselves
+func (m *Machine) runCommands() {
+	for {
+		m.lock.Lock()
+		commands := m.commands
+		m.commands = nil
+		m.lock.Unlock()
+
+		if len(commands) == 0 {
+			return
+		}
+		for _, f := range commands {
+			f(m)
+		}
+	}
+}
+
+// period is the time between the start of two frames
+func (m *Machine) period() time.Duration {
+	if m.speed <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / FrameRate / m.speed)
+}
+
+// measure updates the rates of the statistics once StatsInterval has passed
+func (m *Machine) measure(now time.Time) {
+	elapsed := now.Sub(m.window.start)
+	if elapsed < StatsInterval {
+		return
+	}
+
+	frames := m.frames - m.window.frames
+	m.stats.FramesPerSecond = float64(frames) / elapsed.Seconds()
+	m.stats.InstructionsPerSecond = float64(m.instructions-m.window.instructions) / elapsed.Seconds()
+	m.stats.FrameTime = 0
+	if frames > 0 {
+		m.stats.FrameTime = m.window.busy / time.Duration(frames)
+	}
+	m.startWindow(now)
+}
+
+func (m *Machine) startWindow(now time.Time) {
+	m.window = statsWindow{start: now, instructions: m.instructions, frames: m.frames}
+}
+
+func (m *Machine) reset(hard bool) {
+	m.Cpu.Reset(hard)
+	if hard {
+		copy(m.Cpu.Memory[0x200:], m.rom)
+		m.Cpu.InvalidateCache()
+	}
+	m.halted = false
+	m.Frontend.Render()
+}
+
+// runFrame finishes the current frame, which may have been started by
+// stepping single instructions, and shows it
+func (m *Machine) runFrame() {
+	m.finishFrame()
+	if m.RunAhead > 0 && !m.halted {
+		m.runAhead()
+		return
+	}
+	m.Frontend.Render()
+}
+
+func (m *Machine) finishFrame() {
+	for frame := m.Frame; m.Frame == frame && !m.halted; {
+		m.advance(m.SkipIdle)
+	}
+}
+
+// runAhead shows the screen RunAhead frames from now, as if the keys stayed
+// as they are, and then returns to the present. A key press becomes visible
+// as soon as the program reacts to it instead of frames later. The frames
+// run ahead are neither traced nor recorded.
+func (m *Machine) runAhead() {
+	s := m.Snapshot()
+	tracer := m.Cpu.Tracer()
+	m.Cpu.SetTracer(nil)
+	m.ahead = true
+	for i := 0; i < m.RunAhead && !m.halted; i++ {
+		m.finishFrame()
+	}
+	m.Frontend.Render()
+
+	m.ahead = false
+	m.Cpu.SetTracer(tracer)
+	// The snapshot was taken from this cpu, so it always fits
+	_ = m.restore(s)
+}
+
+// stepInstruction runs one instruction and ends the frame after every
+// InstructionsPerFrame instructions
+func (m *Machine) stepInstruction() {
+	m.advance(false)
+}
+
+// advance runs one instruction or, if idle is set and the cpu is idle, all
+// instructions up to the end of the frame
+func (m *Machine) advance(idle bool) {
+	if m.halted {
+		return
+	}
+	if m.step == 0 && !m.ahead {
+		m.Keyboard.SetFrame(m.Frame)
+	}
+
+	n := 0
+	if idle {
+		n = m.Cpu.SkipIdle(m.InstructionsPerFrame - m.step)
+	}
+	if n == 0 {
+		m.Cpu.Step()
+		n = 1
+	}
+	m.step += n
+	if !m.ahead {
+		m.instructions += uint64(n)
+	}
+	if int(m.Cpu.PC)+1 >= len(m.Cpu.Memory) {
+		m.halted = true
+		return
+	}
+
+	if m.step >= m.InstructionsPerFrame {
+		m.endFrame()
+	}
+}
+
+// endFrame ticks the timers, the screen is shown by the caller
+func (m *Machine) endFrame() {
+	m.Cpu.DecrementTimers()
+	m.Frame++
+	m.step = 0
+	if !m.ahead {
+		m.frames++
+		if m.OnFrame != nil {
+			m.OnFrame()
+		}
+	}
+}
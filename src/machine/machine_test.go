@@ -0,0 +1,278 @@
+package machine
+
+import (
+	"chip8/src/chip8"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type frontend struct {
+	chip8.Framebuffer
+	quit bool
+	// shown has the pixels of every rendered frame
+	shown [][chip8.ScreenWidth * chip8.ScreenHeight]bool
+}
+
+func (f *frontend) Render() { f.shown = append(f.shown, f.Pixels) }
+
+func (f *frontend) PumpEvents()         {}
+func (f *frontend) QuitRequested() bool { return f.quit }
+func (f *frontend) Paused() bool        { return false }
+
+type keys struct{}
+
+func (keys) IsDown(_ uint8) bool { return false }
+func (keys) SetFrame(_ uint64)   {}
+
+// counter increments V0 forever: ADD V0, 1; JP 0x200
+var counter = []byte{0x70, 0x01, 0x12, 0x00}
+
+func start(t *testing.T, rom []byte, paused bool) (*Machine, *frontend, chan error, context.CancelFunc) {
+	f := &frontend{}
+	m, err := New(0x300, rom, f, keys{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.InstructionsPerFrame = 4
+	m.paused = paused
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx) }()
+	return m, f, done, cancel
+}
+
+func state(m *Machine) (pc uint16, v0 uint8, frame uint64) {
+	m.Do(func(m *Machine) { pc, v0, frame = m.Cpu.PC, m.Cpu.V[0], m.Frame })
+	return
+}
+
+func TestMachine_Stepping(t *testing.T) {
+	m, _, done, cancel := start(t, counter, true)
+
+	m.StepInstruction()
+	if pc, v0, frame := state(m); pc != 0x202 || v0 != 1 || frame != 0 {
+		t.Errorf("after one instruction PC=%#x V0=%d frame=%d", pc, v0, frame)
+	}
+
+	// The frame ends after 4 instructions, 3 of them are still missing
+	m.StepFrame()
+	if pc, v0, frame := state(m); pc != 0x200 || v0 != 2 || frame != 1 {
+		t.Errorf("after finishing the frame PC=%#x V0=%d frame=%d", pc, v0, frame)
+	}
+
+	m.StepFrame()
+	if _, v0, frame := state(m); v0 != 4 || frame != 2 {
+		t.Errorf("after another frame V0=%d frame=%d", v0, frame)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run returned %v after cancelling", err)
+	}
+}
+
+func TestMachine_OnPaused(t *testing.T) {
+	m, err := New(0x300, counter, &frontend{}, keys{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.paused = true
+	calls := make(chan uint64, 1)
+	m.OnPaused = func() {
+		select {
+		case calls <- m.Frame:
+		default:
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = m.Run(ctx) }()
+
+	select {
+	case frame := <-calls:
+		if frame != 0 {
+			t.Errorf("OnPaused was called in frame %d of a paused machine", frame)
+		}
+	case <-time.After(time.Second):
+		t.Error("OnPaused was not called while paused")
+	}
+}
+
+func TestMachine_Reset(t *testing.T) {
+	m, _, done, cancel := start(t, counter, true)
+	defer cancel()
+	m.StepFrame()
+	m.Do(func(m *Machine) { m.Cpu.Memory[0x201] = 0x02 })
+
+	m.Reset(false)
+	m.StepFrame()
+	if _, v0, _ := state(m); v0 != 4 {
+		t.Errorf("soft reset: V0 = %d, want 4 with the modified program", v0)
+	}
+
+	m.Reset(true)
+	m.StepFrame()
+	if _, v0, _ := state(m); v0 != 2 {
+		t.Errorf("hard reset: V0 = %d, want 2 with the original program", v0)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestMachine_Speed(t *testing.T) {
+	m, f, done, cancel := start(t, counter, false)
+	defer cancel()
+
+	m.Do(func(m *Machine) {
+		m.SetSpeed(0)
+		m.Frames = m.Frame + 600
+	})
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run returned %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("600 frames took more than a second at unlimited speed")
+	}
+
+	if f.quit {
+		t.Error("frontend asked to quit")
+	}
+}
+
+func TestMachine_Quit(t *testing.T) {
+	m, _, done, cancel := start(t, counter, false)
+	defer cancel()
+
+	m.Do(func(m *Machine) { m.Frontend.(*frontend).quit = true })
+	if err := <-done; err != nil {
+		t.Errorf("Run returned %v after quitting", err)
+	}
+}
+
+func TestMachine_LeavingMemory(t *testing.T) {
+	// JP 0x2FE runs into the end of memory
+	m, _, done, cancel := start(t, []byte{0x12, 0xFE}, false)
+	defer cancel()
+
+	m.SetSpeed(0)
+	if err := <-done; err != nil {
+		t.Errorf("Run returned %v", err)
+	}
+	if m.Frame != 0 {
+		t.Errorf("frame = %d, want 0", m.Frame)
+	}
+}
+
+func TestMachine_SnapshotRestore(t *testing.T) {
+	m, f, done, cancel := start(t, counter, true)
+	m.StepFrame()
+	m.StepInstruction()
+
+	var s Snapshot
+	m.Do(func(m *Machine) {
+		f.SetPixel(3, 4, true)
+		s = m.Snapshot()
+		m.runFrame()
+		f.Clear()
+		if err := m.Restore(s); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if pc, v0, frame := state(m); pc != 0x202 || v0 != 3 || frame != 1 {
+		t.Errorf("after restoring PC=%#x V0=%d frame=%d", pc, v0, frame)
+	}
+	m.Do(func(m *Machine) {
+		if m.step != 1 || !f.GetPixel(3, 4) {
+			t.Errorf("after restoring step=%d pixel=%t", m.step, f.GetPixel(3, 4))
+		}
+	})
+
+	cancel()
+	<-done
+}
+
+func TestMachine_RunAhead(t *testing.T) {
+	// Draws the digit V0 at V0, V0 every frame and reads a random number:
+	// CLS; LD F, V0; DRW V0, V0, 5; ADD V0, 1; RND V1, 0xFF; JP 0x200
+	rom := []byte{0x00, 0xE0, 0xF0, 0x29, 0xD0, 0x05, 0x70, 0x01, 0xC1, 0xFF, 0x12, 0x00}
+	run := func(runAhead int, frames int) (*frontend, []chip8.State) {
+		f := &frontend{}
+		m, err := New(0x300, rom, f, keys{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.InstructionsPerFrame = 6
+		m.RunAhead = runAhead
+		m.Cpu.SetRng(chip8.NewSeededRng(1))
+
+		var states []chip8.State
+		m.OnFrame = func() { states = append(states, m.Cpu.Snapshot()) }
+		for i := 0; i < frames; i++ {
+			m.runFrame()
+		}
+		if m.Frame != uint64(frames) {
+			t.Errorf("run ahead %d: frame = %d, want %d", runAhead, m.Frame, frames)
+		}
+		return f, states
+	}
+
+	plain, plainStates := run(0, 12)
+	ahead, aheadStates := run(2, 10)
+
+	if len(ahead.shown) != 10 {
+		t.Fatalf("rendered %d frames, want 10", len(ahead.shown))
+	}
+	for i, pixels := range ahead.shown {
+		if pixels != plain.shown[i+2] {
+			t.Errorf("frame %d does not show frame %d", i, i+2)
+		}
+	}
+	if !reflect.DeepEqual(aheadStates, plainStates[:10]) {
+		t.Error("running ahead changed the state of the cpu")
+	}
+}
+
+func TestMachine_Stats(t *testing.T) {
+	m, err := New(0x300, counter, &frontend{}, keys{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.InstructionsPerFrame = 4
+	start := time.Now()
+	m.startWindow(start)
+
+	for i := 0; i < 30; i++ {
+		m.runFrame()
+	}
+	m.measure(start.Add(StatsInterval / 2))
+	if s := m.Stats(); s.FramesPerSecond != 0 || s.Frame != 30 || s.Instructions != 120 {
+		t.Errorf("stats before the interval passed: %+v", s)
+	}
+
+	m.measure(start.Add(StatsInterval))
+	if s := m.Stats(); s.FramesPerSecond != 30 || s.InstructionsPerSecond != 120 {
+		t.Errorf("stats after the interval: %+v", s)
+	}
+}
+
+func TestMachine_StatsTimers(t *testing.T) {
+	// LD V0, 5; LD DT, V0; LD V1, K
+	m, err := New(0x300, []byte{0x60, 0x05, 0xF0, 0x15, 0xF1, 0x0A}, &frontend{}, keys{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.InstructionsPerFrame = 4
+
+	m.runFrame()
+	if s := m.Stats(); s.DT != 4 || s.ST != 0 || !s.WaitingForKey {
+		t.Errorf("DT=%d ST=%d waiting=%t, want 4, 0 and waiting", s.DT, s.ST, s.WaitingForKey)
+	}
+}
@@ -1,73 +1,580 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"chip8/src/chip8"
+	"chip8/src/config"
+	"chip8/src/coverage"
 	"chip8/src/displays"
+	"chip8/src/headless"
+	"chip8/src/input"
+	"chip8/src/machine"
+	"chip8/src/movie"
+	"chip8/src/profiling"
+	"chip8/src/savestate"
+	"chip8/src/terminal"
+	"chip8/src/tracing"
+	"chip8/src/video"
+	"context"
+	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 )
 
+const usage = `usage: chip8 <command> [flags] [arguments]
+
+Commands:
+  run        run a rom, also used when the first argument is a flag or a file
+  debug      step through a rom in a line based debugger
+  disasm     disassemble a rom
+  asm        assemble a rom
+  recompile  translate a rom into Go source
+  info       show what a rom contains
+  selftest   run the embedded conformance roms
+  tracediff  compare two execution traces
+  lockstep   run a rom with several quirk profiles and report where they diverge
+  coverage   report which parts of a rom were executed
+  states     list, remove, export and import save states
+
+Run chip8 <command> -h for the flags of a command.
+
+Exit status is 0 on success, 1 when the command ran but failed, e.g. a test
+did not pass, traces diverged or a run was interrupted, and 2 for invalid
+arguments or input files.
+`
+
+// commands maps subcommand names to their implementation
+var commands = map[string]func(args []string) int{
+	"run":       run,
+	"debug":     runDebug,
+	"disasm":    disassemble,
+	"asm":       assemble,
+	"recompile": recompileRom,
+	"info":      romInfo,
+	"selftest":  runSelftest,
+	"tracediff": traceDiff,
+	"lockstep":  runLockstep,
+	"coverage":  coverageReport,
+	"states":    runStates,
+}
+
 func main() {
-	display, err := displays.NewSDLRenderer(32)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if command, ok := commands[name]; ok {
+		os.Exit(command(os.Args[2:]))
+	}
+
+	switch {
+	case name == "help" || name == "-h" || name == "-help" || name == "--help":
+		fmt.Print(usage)
+		os.Exit(0)
+	case strings.HasPrefix(name, "-") || isFile(name):
+		os.Exit(run(os.Args[1:]))
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", name, usage)
+	os.Exit(2)
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// run implements `chip8 run [flags] rom.ch8`. It returns 0 when the rom
+// ran until the window was closed or all frames were run, 1 when the display
+// could not be opened, the run was interrupted or the trace, profile,
+// coverage map or movie could not be written and 2 on invalid arguments.
+func run(args []string) (status int) {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	romFlag := flags.String("rom", "", "rom to run, instead of the positional argument")
+	quirksName := flags.String("quirks", "default", "quirk profile ("+strings.Join(chip8.QuirkProfileNames(), ", ")+")")
+	memorySize := flags.Int("memory", chip8.DefaultMemorySize, "memory size in bytes")
+	configPath := flags.String("config", "", "config file, defaults to "+defaultConfigPath()+", none to ignore it")
+	trace := flags.String("trace", "", "write an execution trace to this file")
+	profile := flags.String("profile", "", "write a profiling report to this file on exit")
+	folded := flags.String("folded", "", "write folded call stacks for flamegraph tools to this file on exit")
+	coverageOut := flags.String("coverage", "", "write a JSON coverage map to this file on exit")
+	headlessRun := flags.Bool("headless", false, "run without a window, the same as -display headless")
+	frames := flags.Uint64("frames", 600, "number of frames to run in headless mode")
+	screenshot := flags.String("screenshot", "", "write the final frame of a headless run to this PNG file")
+	scale := flags.Int("scale", 0, "pixel size, 0 picks 32 for windows and 8 for screenshots and sixel images")
+	keys := flags.String("keys", "", "scripted key presses for headless mode, e.g. 30:5,32:,60:4a")
+	ipf := flags.Int("ipf", 10, "instructions per frame")
+	decodeCache := flags.Bool("decode-cache", true, "run instructions from a cache of decoded instructions")
+	skipIdle := flags.Bool("skip-idle", true, "skip loops that only wait for the next timer tick")
+	seed := flags.Uint64("seed", 0, "seed for the random number generator, 0 picks one")
+	record := flags.String("record", "", "record input into this movie file")
+	play := flags.String("play", "", "play back input from this movie file")
+	hotkeySpec := flags.String("hotkeys", "default", "hotkey file, default or none")
+	statesDir := flags.String("states", defaultStatesDir(), "save state directory")
+	fastForward := flags.Float64("fast-forward", 4, "speed of fast forward, 0 for as fast as possible")
+	showStats := flags.Bool("stats", false, "show frame rate, instructions per second and timers, the show-stats hotkey toggles them")
+	runAhead := flags.Int("run-ahead", 0, "frames to show ahead of the emulated state to hide input lag")
+	keymapSpec := flags.String("keymap", input.DefaultPreset, "keymap preset (qwerty, azerty, numpad) or keymap file")
+	padmapSpec := flags.String("padmap", "", "game controller mapping preset or file, defaults to the rom path with a .padmap extension if that exists")
+	paletteSpec := flags.String("palette", video.DefaultPalette, "colour palette ("+strings.Join(video.PaletteNames(), ", ")+") or foreground,background hex colours")
+	filterName := flags.String("filter", "none", "pixel art upscaler ("+strings.Join(video.FilterNames(), ", ")+")")
+	scaling := flags.String("scaling", "fit", "window scaling, fit or integer")
+	backend := flags.String("display", "auto", "where to draw: auto, "+strings.Join(backends, ", ")+" or headless")
+	cells := flags.String("cells", terminal.Auto, "characters used by the terminal display: auto, "+strings.Join(terminal.EncoderNames(), ", "))
+	persistence := flags.String("persistence", "off", "phosphor effect against flicker: off, blend or fade:N to fade pixels out over N frames")
+	_ = flags.Parse(args)
+
+	romPath, rom, err := readRom(flags, *romFlag)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(-1)
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := applyConfig(flags, *configPath, rom, true); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
 	}
-	defer display.Dispose()
-	cpu := chip8.NewCPU(0xFFF, display, display)
 
-	/*
-		cpu.LoadProgram(bytes.NewReader([]byte{
-			0x72, 0x05,
-			0x73, 0x1B,
-			0x61, 0x00,
-			0xA0, 0x00,
-			0xD0, 0x15, // Display 0
-			0xD0, 0x35,
+	if *backend == "headless" {
+		*headlessRun = true
+	}
+	if *headlessRun && *scale == 0 {
+		*scale = 8
+	}
+	if *memorySize <= 0x200 || *memorySize > 0x7FFF {
+		fmt.Fprintf(os.Stderr, "memory size must be more than %d and at most %d bytes\n", 0x200, 0x7FFF)
+		return 2
+	}
 
-			// 0xF2, 0x1E, // Increment I by 5
-			0x70, 0x05,
+	if *runAhead < 0 {
+		fmt.Fprintln(os.Stderr, "run-ahead must not be negative")
+		return 2
+	}
 
-			0x12, 0x06,
+	if *seed == 0 {
+		*seed = uint64(time.Now().UnixNano())
+	}
 
+	quirks, ok := chip8.QuirkProfile(*quirksName)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown quirk profile %q\n", *quirksName)
+		return 2
+	}
+
+	var player *movie.Player
+	if *play != "" {
+		m, err := readMovie(*play)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if err := m.CheckRom(rom); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		*seed = m.Seed
+		*ipf = m.InstructionsPerFrame
+		quirks = m.Quirks
+		if !flagSet(flags, "frames") {
+			*frames = uint64(len(m.Frames))
+		}
+		player = movie.NewPlayer(m)
+	}
 
-		})) */
+	screen, err := newVideo(*paletteSpec, *filterName, *persistence)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
 
-	f, err := os.Open("./games/BLINKY.ch8")
+	var live chip8.Keyboard
+	var display window
+	var m *machine.Machine
+	var actions *hotkeys
+	if *headlessRun {
+		events, err := headless.ParseKeyScript(*keys)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		live = &headless.ScriptedKeyboard{Events: events}
+	} else {
+		bindings, err := loadHotkeys(*hotkeySpec)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		actions = &hotkeys{
+			screen:      screen,
+			romPath:     romPath,
+			romHash:     movie.RomHash(rom),
+			fastForward: *fastForward,
+			states:      savestate.Store{Dir: *statesDir},
+			recording:   *record != "",
+			showStats:   *showStats,
+		}
 
-	err = cpu.LoadProgram(f)
+		display, live, err = openWindow(windowOptions{
+			backend: *backend,
+			screen:  screen,
+			scaling: *scaling,
+			cells:   *cells,
+			scale:   *scale,
+			keymap:  *keymapSpec,
+			padmap:  *padmapSpec,
+			romPath: romPath,
+			hotkeys: bindings,
+			// Hotkeys arrive while the machine pumps events, so they are
+			// handled once it has finished pumping
+			onHotkey: func(a input.Action, down bool) {
+				m.Post(func(m *machine.Machine) { actions.handle(m, a, down) })
+			},
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer display.Dispose()
+		actions.window = display
+	}
+
+	var keyboard headless.FrameKeyboard = liveKeyboard{live}
+	if k, ok := live.(headless.FrameKeyboard); ok {
+		keyboard = k
+	}
+	if player != nil {
+		keyboard = player
+	} else if *record != "" {
+		m := &movie.Movie{RomHash: movie.RomHash(rom), Seed: *seed, Quirks: quirks, InstructionsPerFrame: *ipf}
+		keyboard = movie.NewRecorder(live, m)
+		defer func() { status = failOnError(status, writeMovie(m, *record)) }()
+	}
+
+	var cpu *chip8.Cpu
+	var runner *headless.Runner
+	if *headlessRun {
+		runner = headless.NewRunnerWithKeyboard(int16(*memorySize), keyboard)
+		runner.InstructionsPerFrame = *ipf
+		runner.SkipIdle = *skipIdle
+		cpu = runner.Cpu
+		err = cpu.LoadProgram(bytes.NewReader(rom))
+	} else {
+		m, err = machine.New(int16(*memorySize), rom, display, keyboard)
+		if err == nil {
+			m.InstructionsPerFrame = *ipf
+			m.RunAhead = *runAhead
+			m.SkipIdle = *skipIdle
+			cpu = m.Cpu
+		}
+	}
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	cpu.Quirks = quirks
+	cpu.SetRng(chip8.NewSeededRng(*seed))
+	cpu.SetDecodeCache(*decodeCache)
+
+	var tracers chip8.MultiTracer
+	if *trace != "" {
+		t, err := os.Create(*trace)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		w := bufio.NewWriter(t)
+		tw := &tracing.Writer{To: w}
+		defer func() { status = failOnError(status, closeTrace(t, w, tw)) }()
+		tracers = append(tracers, tw)
+	}
+
+	var profiler *profiling.Profiler
+	if *profile != "" || *folded != "" {
+		profiler = profiling.NewProfiler()
+		tracers = append(tracers, profiler)
+		defer func() { status = failOnError(status, writeProfile(profiler, *profile, *folded)) }()
+	}
+
+	if *coverageOut != "" {
+		m := coverage.NewMap(len(cpu.Memory))
+		tracers = append(tracers, m)
+		defer func() { status = failOnError(status, writeCoverage(m, *coverageOut)) }()
+	}
+
+	if len(tracers) > 0 {
+		cpu.SetTracer(tracers)
+	}
+
+	if runner != nil {
+		return runHeadless(runner, profiler, screen, *frames, *screenshot, *scale)
+	}
+
+	if player != nil {
+		m.Frames = uint64(len(player.Movie.Frames))
+	}
+	m.OnFrame = func() {
+		if profiler != nil {
+			profiler.Frame()
+		}
+		actions.frame(m)
+	}
+	m.OnPaused = func() {
+		actions.frame(m)
+	}
+
+	// Interrupting the process stops the machine like closing the window does,
+	// so the deferred movie, profile and coverage writers still run
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := m.Run(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "interrupted")
+		return 1
+	}
+	return 0
+}
+
+// readRom reads the rom named by the -rom flag or the only positional argument
+func readRom(flags *flag.FlagSet, romFlag string) (string, []byte, error) {
+	path := romFlag
+	switch {
+	case path != "" && flags.NArg() > 0:
+		return "", nil, fmt.Errorf("the rom is given both with -rom and as an argument")
+	case path == "" && flags.NArg() != 1:
+		return "", nil, fmt.Errorf("usage: chip8 %s [flags] rom.ch8", flags.Name())
+	case path == "":
+		path = flags.Arg(0)
+	}
+
+	rom, err := ioutil.ReadFile(path)
+	return path, rom, err
+}
+
+func defaultConfigPath() string {
+	path, err := config.DefaultPath()
+	if err != nil {
+		return "none"
+	}
+	return path
+}
+
+// applyConfig sets every flag that was not given on the command line from the
+// config file at path, or from the default config file if path is empty.
+// Settings for flags that do not exist are an error if reportUnknown is set
+// and ignored otherwise, so that subcommands can share the config of run.
+func applyConfig(flags *flag.FlagSet, path string, rom []byte, reportUnknown bool) error {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	if path == "none" {
+		return nil
 	}
 
-	rate := int64(16)
-	last := time.Now()
+	c, err := config.Load(path)
+	if os.IsNotExist(err) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
 
-	// dumper := statedumpers.TableDumper{To: os.Stdout}
+	unknown, err := config.Apply(flags, c.For(movie.RomHash(rom)))
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	if len(unknown) > 0 && reportUnknown {
+		return fmt.Errorf("%s: unknown setting %q", path, unknown[0])
+	}
+	return nil
+}
 
-	memory := uint32(len(cpu.Memory))
-	step := 0
-	render := 0
-	for true {
-		cpu.Step()
-		step++
+// liveKeyboard is a keyboard that is read directly instead of once per frame
+type liveKeyboard struct {
+	chip8.Keyboard
+}
 
-		//dumper.DumpState(cpu)
+func (liveKeyboard) SetFrame(_ uint64) {
+}
 
-		t := time.Since(last)
-		if t.Milliseconds() > rate {
-			fmt.Printf("Step: %015d\tRendered Frame:%010d\r", step, render)
-			last = time.Now()
-			cpu.DecrementTimers()
-			render++
+func flagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
 		}
-		display.Render()
-		//statedumpers.TableDumper{To: os.Stdout}.DumpState(cpu)
-		if uint32(cpu.PC) > memory {
+	})
+	return set
+}
+
+func runHeadless(runner *headless.Runner, profiler *profiling.Profiler, screen *video.Renderer, frames uint64, screenshot string, scale int) int {
+	pixels := runner.Display.Pixels[:]
+	for i := uint64(0); i < frames; i++ {
+		if !runner.RunFrames(1) {
+			fmt.Fprintf(os.Stderr, "Program counter left memory after %d frames\n", runner.Frame)
 			break
 		}
+		if profiler != nil {
+			profiler.Frame()
+		}
+		// The phosphor effect depends on every frame, not just the last one
+		if screen.Persistence != nil {
+			screen.Persistence.Update(pixels)
+		}
+	}
+
+	if screenshot == "" {
+		return 0
+	}
+
+	if err := writePNG(screenshot, screenImage(screen, pixels), scale); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// closeTrace flushes and closes the trace file f and returns the first error
+// of writing, flushing or closing it
+func closeTrace(f *os.File, b *bufio.Writer, w *tracing.Writer) error {
+	err := w.Err
+	if flushErr := b.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %s", f.Name(), err)
+	}
+	return nil
+}
+
+// failOnError reports err of writing an output file at the end of a run,
+// which makes a successful run fail with status 1
+func failOnError(status int, err error) int {
+	if err == nil {
+		return status
+	}
+	fmt.Fprintln(os.Stderr, err)
+	if status == 0 {
+		return 1
+	}
+	return status
+}
+
+func writeProfile(profiler *profiling.Profiler, report string, folded string) error {
+	if report != "" {
+		f, err := os.Create(report)
+		if err != nil {
+			return err
+		}
+		profiler.WriteReport(f, 20)
+		if err := f.Close(); err != nil {
+			return err
+		}
 	}
 
-	//dumper.DumpState(cpu)
+	if folded != "" {
+		f, err := os.Create(folded)
+		if err != nil {
+			return err
+		}
+		if err := profiler.WriteFolded(f); err != nil {
+			f.Close()
+			return fmt.Errorf("%s: %s", folded, err)
+		}
+		return f.Close()
+	}
+	return nil
+}
+
+// newVideo sets up the palette, upscaler and phosphor effect shared by the window and screenshots
+func newVideo(paletteSpec string, filterName string, persistenceSpec string) (*video.Renderer, error) {
+	palette, err := video.ParsePalette(paletteSpec)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := video.ParseFilter(filterName)
+	if err != nil {
+		return nil, err
+	}
+	persistence, err := video.ParsePersistence(persistenceSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	screen := video.NewRenderer(palette, filter)
+	screen.Persistence = persistence
+	return screen, nil
+}
+
+func configureVideo(display *displays.NewSDLDisplay, screen *video.Renderer, scaling string) error {
+	display.SetPalette(screen.Palette)
+	display.SetPersistence(screen.Persistence)
+	if err := display.SetFilter(screen.Filter); err != nil {
+		return err
+	}
+
+	switch scaling {
+	case "fit":
+		return display.SetIntegerScaling(false)
+	case "integer":
+		return display.SetIntegerScaling(true)
+	}
+	return fmt.Errorf("unknown scaling %q, expected fit or integer", scaling)
+}
+
+// loadKeymap returns the preset called spec, or reads spec as a keymap file
+func loadKeymap(spec string) (input.Keymap, error) {
+	if keymap, ok := input.Preset(spec); ok {
+		return keymap, nil
+	}
+
+	f, err := os.Open(spec)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	keymap, err := input.ReadKeymap(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", spec, err)
+	}
+	return keymap, nil
+}
+
+// loadPadmap returns the gamepad mapping preset or file called spec. Without
+// a spec the rom specific file next to the rom is used if there is one.
+func loadPadmap(spec string, romPath string) (input.Keymap, error) {
+	if spec == "" {
+		spec = input.DefaultPadPreset
+		romSpecific := strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".padmap"
+		if _, err := os.Stat(romSpecific); err == nil {
+			spec = romSpecific
+		}
+	}
+
+	if padmap, ok := input.PadPreset(spec); ok {
+		return padmap, nil
+	}
+
+	f, err := os.Open(spec)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	padmap, err := input.ReadPadmap(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", spec, err)
+	}
+	return padmap, nil
 }
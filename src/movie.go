@@ -0,0 +1,33 @@
+package main
+
+import (
+	"chip8/src/movie"
+	"fmt"
+	"os"
+)
+
+func readMovie(path string) (*movie.Movie, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := movie.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return m, nil
+}
+
+func writeMovie(m *movie.Movie, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := m.Write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,76 @@
+package movie
+
+import (
+	"chip8/src/chip8"
+)
+
+// Recorder is a keyboard that samples Live once per frame and stores the
+// result in Movie. The cpu only ever sees the sampled state, which is what
+// makes playback exact.
+type Recorder struct {
+	Live  chip8.Keyboard
+	Movie *Movie
+
+	keys uint16
+}
+
+func NewRecorder(live chip8.Keyboard, m *Movie) *Recorder {
+	return &Recorder{Live: live, Movie: m}
+}
+
+// SetFrame samples the live keyboard for the frame about to run and stores
+// it as entry frame of the movie, dropping any later entries. Frames that
+// were skipped have no keys held. Live keyboards that have a SetFrame method
+// of their own are advanced first.
+func (r *Recorder) SetFrame(frame uint64) {
+	if live, ok := r.Live.(interface{ SetFrame(uint64) }); ok {
+		live.SetFrame(frame)
+	}
+
+	r.keys = 0
+	for k := uint8(0); k < 0x10; k++ {
+		if r.Live.IsDown(k) {
+			r.keys |= 1 << k
+		}
+	}
+	if frame < uint64(len(r.Movie.Frames)) {
+		r.Movie.Frames = r.Movie.Frames[:frame]
+	}
+	for uint64(len(r.Movie.Frames)) < frame {
+		r.Movie.Frames = append(r.Movie.Frames, 0)
+	}
+	r.Movie.Frames = append(r.Movie.Frames, r.keys)
+}
+
+func (r *Recorder) IsDown(key uint8) bool {
+	return key < 0x10 && r.keys&(1<<key) != 0
+}
+
+// Player is a keyboard replaying a Movie
+type Player struct {
+	Movie *Movie
+
+	keys uint16
+}
+
+func NewPlayer(m *Movie) *Player {
+	return &Player{Movie: m}
+}
+
+// SetFrame loads the recorded keypad state of frame. Frames past the end of
+// the movie have no keys held.
+func (p *Player) SetFrame(frame uint64) {
+	p.keys = 0
+	if frame < uint64(len(p.Movie.Frames)) {
+		p.keys = p.Movie.Frames[frame]
+	}
+}
+
+// Done reports whether frame is past the end of the recording
+func (p *Player) Done(frame uint64) bool {
+	return frame >= uint64(len(p.Movie.Frames))
+}
+
+func (p *Player) IsDown(key uint8) bool {
+	return key < 0x10 && p.keys&(1<<key) != 0
+}
@@ -0,0 +1,164 @@
+package movie
+
+import (
+	"bufio"
+	"chip8/src/chip8"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const header = "CHIP8MOVIE 2"
+
+// Movie holds everything needed to replay a run exactly: the configuration
+// and the keypad state of every frame.
+type Movie struct {
+	RomHash              string
+	Seed                 uint64
+	Quirks               chip8.Quirks
+	InstructionsPerFrame int
+
+	// Frames holds one bit per key for every frame, bit n is key n
+	Frames []uint16
+}
+
+// RomHash returns the hash stored in movies to identify rom
+func RomHash(rom []byte) string {
+	sum := sha256.Sum256(rom)
+	return hex.EncodeToString(sum[:])
+}
+
+// IsRomHash reports whether s looks like a hash returned by RomHash, in
+// upper or lower case
+func IsRomHash(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, c := range strings.ToLower(s) {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckRom returns an error if the movie was not recorded with rom
+func (m *Movie) CheckRom(rom []byte) error {
+	if hash := RomHash(rom); hash != m.RomHash {
+		return fmt.Errorf("movie was recorded with rom %s, not %s", m.RomHash, hash)
+	}
+	return nil
+}
+
+func bit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Write stores the movie as text. Frames are run length encoded as
+// "count:mask" lines so long stretches without input stay small.
+func (m *Movie) Write(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, header)
+	fmt.Fprintf(b, "rom %s\n", m.RomHash)
+	fmt.Fprintf(b, "seed %d\n", m.Seed)
+	fmt.Fprintf(b, "quirks %d %d %d %d\n",
+		bit(m.Quirks.ShiftUsesVy), bit(m.Quirks.LoadStoreIncrementsI), bit(m.Quirks.JumpUsesVx), bit(m.Quirks.VFReset))
+	fmt.Fprintf(b, "ipf %d\n", m.InstructionsPerFrame)
+
+	fmt.Fprintf(b, "frames %d\n", len(m.Frames))
+	for i := 0; i < len(m.Frames); {
+		n := 1
+		for i+n < len(m.Frames) && m.Frames[i+n] == m.Frames[i] {
+			n++
+		}
+		fmt.Fprintf(b, "%d:%04x\n", n, m.Frames[i])
+		i += n
+	}
+
+	return b.Flush()
+}
+
+// Read parses a movie written by Write
+func Read(r io.Reader) (*Movie, error) {
+	m := &Movie{}
+	scanner := bufio.NewScanner(r)
+
+	line := func() (string, error) {
+		if !scanner.Scan() {
+			if scanner.Err() != nil {
+				return "", scanner.Err()
+			}
+			return "", io.ErrUnexpectedEOF
+		}
+		return scanner.Text(), nil
+	}
+
+	l, err := line()
+	if err != nil {
+		return nil, err
+	}
+	if l != header {
+		return nil, fmt.Errorf("not a movie file")
+	}
+
+	fields := map[string]string{}
+	for _, key := range []string{"rom", "seed", "quirks", "ipf", "frames"} {
+		l, err := line()
+		if err != nil {
+			return nil, err
+		}
+		parts := strings.SplitN(l, " ", 2)
+		if parts[0] != key {
+			return nil, fmt.Errorf("expected %q, got %q", key, l)
+		}
+		if len(parts) == 2 {
+			fields[key] = parts[1]
+		}
+	}
+
+	m.RomHash = fields["rom"]
+	if m.Seed, err = strconv.ParseUint(fields["seed"], 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid seed: %s", err)
+	}
+
+	var q [4]int
+	if _, err := fmt.Sscanf(fields["quirks"], "%d %d %d %d", &q[0], &q[1], &q[2], &q[3]); err != nil {
+		return nil, fmt.Errorf("invalid quirks: %s", err)
+	}
+	m.Quirks = chip8.Quirks{ShiftUsesVy: q[0] == 1, LoadStoreIncrementsI: q[1] == 1, JumpUsesVx: q[2] == 1, VFReset: q[3] == 1}
+
+	if m.InstructionsPerFrame, err = strconv.Atoi(fields["ipf"]); err != nil {
+		return nil, fmt.Errorf("invalid ipf: %s", err)
+	}
+
+	count, err := strconv.Atoi(fields["frames"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid frame count: %s", err)
+	}
+	m.Frames = make([]uint16, 0, count)
+	for len(m.Frames) < count {
+		l, err := line()
+		if err != nil {
+			return nil, err
+		}
+		var n int
+		var mask uint16
+		if _, err := fmt.Sscanf(l, "%d:%04x", &n, &mask); err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid frame line %q", l)
+		}
+		for i := 0; i < n; i++ {
+			m.Frames = append(m.Frames, mask)
+		}
+	}
+	if len(m.Frames) != count {
+		return nil, fmt.Errorf("expected %d frames, got %d", count, len(m.Frames))
+	}
+
+	return m, nil
+}
@@ -0,0 +1,81 @@
+package movie
+
+import (
+	"bytes"
+	"chip8/src/chip8"
+	"chip8/src/headless"
+	"reflect"
+	"testing"
+)
+
+// rom waits for a key, then keeps drawing random sprites at positions moved by key 5
+var rom = []byte{
+	0xF4, 0x0A, // 0x200 LD V4, K
+	0x60, 0x05, // 0x202 LD V0, 5
+	0xE0, 0xA1, // 0x204 SKNP V0
+	0x71, 0x01, // 0x206 ADD V1, 1
+	0xC2, 0x3F, // 0x208 RND V2, 0x3F
+	0xF2, 0x29, // 0x20A LD F, V2
+	0xD1, 0x25, // 0x20C DRW V1, V2, 5
+	0x12, 0x02, // 0x20E JP 0x202
+}
+
+func newRunner(keyboard headless.FrameKeyboard, m *Movie) *headless.Runner {
+	r := headless.NewRunnerWithKeyboard(0xFFF, keyboard)
+	r.Cpu.Quirks = m.Quirks
+	r.Cpu.SetRng(chip8.NewSeededRng(m.Seed))
+	r.InstructionsPerFrame = m.InstructionsPerFrame
+	_ = r.Cpu.LoadProgram(bytes.NewReader(rom))
+	return r
+}
+
+func TestRecordAndPlayback(t *testing.T) {
+	events, _ := headless.ParseKeyScript("0:3,5:5,12:,20:5")
+	m := &Movie{RomHash: RomHash(rom), Seed: 99, InstructionsPerFrame: 7}
+
+	recording := newRunner(NewRecorder(&headless.ScriptedKeyboard{Events: events}, m), m)
+	recording.RunFrames(30)
+
+	out := bytes.Buffer{}
+	if err := m.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Read(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, m) {
+		t.Fatalf("Movie changed after a round trip:\n%+v\n%+v", loaded, m)
+	}
+	if err := loaded.CheckRom(rom); err != nil {
+		t.Error(err)
+	}
+	if err := loaded.CheckRom([]byte{0x12, 0x00}); err == nil {
+		t.Error("Expected a different rom to be rejected")
+	}
+
+	playback := newRunner(NewPlayer(loaded), loaded)
+	playback.RunFrames(30)
+
+	if fields := chip8.CompareState(recording.Cpu, playback.Cpu); len(fields) > 0 {
+		t.Errorf("Playback diverged from the recording: %v", fields)
+	}
+	if recording.Display.Pixels != playback.Display.Pixels {
+		t.Errorf("Playback drew a different screen")
+	}
+}
+
+func TestRecorder_StoresByFrame(t *testing.T) {
+	m := &Movie{}
+	live := &headless.ScriptedKeyboard{Events: []headless.KeyEvent{{Frame: 0, Keys: []uint8{1}}, {Frame: 2, Keys: []uint8{2}}}}
+	r := NewRecorder(live, m)
+
+	for _, frame := range []uint64{0, 1, 2, 3, 1, 2, 5} {
+		r.SetFrame(frame)
+	}
+
+	// Going back to frame 1 dropped the later frames, frame 4 was skipped
+	if want := []uint16{1 << 1, 1 << 1, 1 << 2, 0, 0, 1 << 2}; !reflect.DeepEqual(m.Frames, want) {
+		t.Errorf("Expected frames %v, got %v", want, m.Frames)
+	}
+}
@@ -0,0 +1,237 @@
+package profiling
+
+import (
+	"chip8/src/chip8"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Subroutine holds the cost of a single CALL target
+type Subroutine struct {
+	Address uint16
+	Calls   uint64
+	// Inclusive counts instructions executed inside the subroutine and everything it called
+	Inclusive uint64
+	// Exclusive counts instructions executed directly by the subroutine
+	Exclusive uint64
+}
+
+// Loop is a backwards jump and how often it was taken
+type Loop struct {
+	From  uint16
+	To    uint16
+	Count uint64
+}
+
+// Profiler collects execution statistics. It is a chip8.Tracer, so attach it
+// with Cpu.SetTracer and call Frame once per timer tick.
+type Profiler struct {
+	Instructions uint64
+	Frames       uint64
+
+	addresses map[uint16]uint64
+	opcodes   map[uint16]uint16
+	classes   map[string]uint64
+	routines  map[uint16]*Subroutine
+	loops     map[[2]uint16]uint64
+	folded    map[string]uint64
+
+	stack []uint16
+}
+
+// unknownFrame is pushed when SP grows without a CALL we saw
+const unknownFrame = 0xFFFF
+
+func NewProfiler() *Profiler {
+	return &Profiler{
+		addresses: map[uint16]uint64{},
+		opcodes:   map[uint16]uint16{},
+		classes:   map[string]uint64{},
+		routines:  map[uint16]*Subroutine{},
+		loops:     map[[2]uint16]uint64{},
+		folded:    map[string]uint64{},
+	}
+}
+
+// Frame marks the end of a frame
+func (p *Profiler) Frame() {
+	p.Frames++
+}
+
+func (p *Profiler) Trace(e chip8.TraceEntry) {
+	p.Instructions++
+	p.addresses[e.PC]++
+	p.opcodes[e.PC] = e.Opcode
+	p.classes[chip8.OpcodeClass(e.Opcode)]++
+
+	// The instruction is charged to the stack as it was before executing it
+	p.folded[p.stackKey()]++
+	seen := map[uint16]bool{}
+	for _, addr := range p.stack {
+		if !seen[addr] {
+			p.routine(addr).Inclusive++
+			seen[addr] = true
+		}
+	}
+	if len(p.stack) > 0 {
+		p.routine(p.stack[len(p.stack)-1]).Exclusive++
+	}
+
+	switch {
+	case e.Opcode&0xF000 == 0x2000:
+		target := e.Opcode & 0x0FFF
+		p.routine(target).Calls++
+		p.stack = append(p.stack, target)
+	case e.Opcode == 0x00EE && len(p.stack) > 0:
+		p.stack = p.stack[:len(p.stack)-1]
+	case e.Opcode&0xF000 == 0x1000 && e.Opcode&0x0FFF <= e.PC:
+		p.loops[[2]uint16{e.PC, e.Opcode & 0x0FFF}]++
+	}
+
+	// Keep our view of the call stack in line with Cpu.S
+	for int(e.SP) > len(p.stack) {
+		p.stack = append(p.stack, unknownFrame)
+	}
+	if int(e.SP) < len(p.stack) {
+		p.stack = p.stack[:e.SP]
+	}
+}
+
+func (p *Profiler) routine(addr uint16) *Subroutine {
+	r, ok := p.routines[addr]
+	if !ok {
+		r = &Subroutine{Address: addr}
+		p.routines[addr] = r
+	}
+	return r
+}
+
+func (p *Profiler) stackKey() string {
+	frames := []string{"main"}
+	for _, addr := range p.stack {
+		frames = append(frames, frameName(addr))
+	}
+	return strings.Join(frames, ";")
+}
+
+func frameName(addr uint16) string {
+	if addr == unknownFrame {
+		return "unknown"
+	}
+	return fmt.Sprintf("sub_%03x", addr)
+}
+
+// Subroutines returns every called subroutine, most expensive first
+func (p *Profiler) Subroutines() []Subroutine {
+	routines := make([]Subroutine, 0, len(p.routines))
+	for _, r := range p.routines {
+		routines = append(routines, *r)
+	}
+	sort.Slice(routines, func(i, j int) bool {
+		if routines[i].Inclusive != routines[j].Inclusive {
+			return routines[i].Inclusive > routines[j].Inclusive
+		}
+		return routines[i].Address < routines[j].Address
+	})
+	return routines
+}
+
+// Loops returns every backwards jump, most taken first
+func (p *Profiler) Loops() []Loop {
+	loops := make([]Loop, 0, len(p.loops))
+	for k, count := range p.loops {
+		loops = append(loops, Loop{From: k[0], To: k[1], Count: count})
+	}
+	sort.Slice(loops, func(i, j int) bool {
+		if loops[i].Count != loops[j].Count {
+			return loops[i].Count > loops[j].Count
+		}
+		return loops[i].From < loops[j].From
+	})
+	return loops
+}
+
+// WriteFolded writes the collected stacks in the folded format understood by
+// flamegraph.pl, inferno and speedscope.
+func (p *Profiler) WriteFolded(w io.Writer) error {
+	stacks := make([]string, 0, len(p.folded))
+	for stack := range p.folded {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+
+	for _, stack := range stacks {
+		if _, err := fmt.Fprintf(w, "%s %d\n", stack, p.folded[stack]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteReport writes a human readable summary limited to the top entries of each table
+func (p *Profiler) WriteReport(w io.Writer, top int) {
+	fmt.Fprintf(w, "Instructions: %d\n", p.Instructions)
+	if p.Frames > 0 {
+		fmt.Fprintf(w, "Frames:       %d (%.1f instructions per frame)\n", p.Frames, float64(p.Instructions)/float64(p.Frames))
+	}
+
+	fmt.Fprintln(w, "\nHot addresses")
+	addresses := make([]uint16, 0, len(p.addresses))
+	for addr := range p.addresses {
+		addresses = append(addresses, addr)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		if p.addresses[addresses[i]] != p.addresses[addresses[j]] {
+			return p.addresses[addresses[i]] > p.addresses[addresses[j]]
+		}
+		return addresses[i] < addresses[j]
+	})
+	for i, addr := range addresses {
+		if i == top {
+			break
+		}
+		op := p.opcodes[addr]
+		fmt.Fprintf(w, "  %#04x  %04x  %-14s %10d  %5.1f%%\n", addr, op, chip8.OpcodeClass(op), p.addresses[addr], p.percent(p.addresses[addr]))
+	}
+
+	fmt.Fprintln(w, "\nOpcode classes")
+	classes := make([]string, 0, len(p.classes))
+	for class := range p.classes {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if p.classes[classes[i]] != p.classes[classes[j]] {
+			return p.classes[classes[i]] > p.classes[classes[j]]
+		}
+		return classes[i] < classes[j]
+	})
+	for _, class := range classes {
+		fmt.Fprintf(w, "  %-14s %10d  %5.1f%%\n", class, p.classes[class], p.percent(p.classes[class]))
+	}
+
+	fmt.Fprintln(w, "\nSubroutines")
+	for i, r := range p.Subroutines() {
+		if i == top {
+			break
+		}
+		fmt.Fprintf(w, "  %s  calls %8d  inclusive %10d (%5.1f%%)  exclusive %10d\n",
+			frameName(r.Address), r.Calls, r.Inclusive, p.percent(r.Inclusive), r.Exclusive)
+	}
+
+	fmt.Fprintln(w, "\nHot loops")
+	for i, l := range p.Loops() {
+		if i == top {
+			break
+		}
+		fmt.Fprintf(w, "  %#04x -> %#04x  %10d iterations\n", l.From, l.To, l.Count)
+	}
+}
+
+func (p *Profiler) percent(n uint64) float64 {
+	if p.Instructions == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(p.Instructions)
+}
@@ -0,0 +1,55 @@
+package profiling
+
+import (
+	"bytes"
+	"chip8/src/chip8"
+	"strings"
+	"testing"
+)
+
+func TestProfiler(t *testing.T) {
+	cpu := chip8.NewCPU(0x300, nil, nil)
+	_ = cpu.LoadProgram(bytes.NewReader([]byte{
+		0x22, 0x06, // 0x200 CALL 0x206
+		0x70, 0x01, // 0x202 ADD V0, 1
+		0x12, 0x00, // 0x204 JP 0x200
+		0x71, 0x01, // 0x206 ADD V1, 1
+		0x00, 0xEE, // 0x208 RET
+	}))
+
+	p := NewProfiler()
+	cpu.SetTracer(p)
+	for i := 0; i < 50; i++ {
+		cpu.Step()
+	}
+
+	if p.Instructions != 50 {
+		t.Errorf("Expected 50 instructions, got %d", p.Instructions)
+	}
+
+	routines := p.Subroutines()
+	if len(routines) != 1 || routines[0].Address != 0x206 || routines[0].Calls != 10 {
+		t.Fatalf("Expected 10 calls to 0x206, got %v", routines)
+	}
+
+	if routines[0].Inclusive != 20 || routines[0].Exclusive != 20 {
+		t.Errorf("Expected 20 instructions in 0x206, got %d/%d", routines[0].Inclusive, routines[0].Exclusive)
+	}
+
+	loops := p.Loops()
+	if len(loops) != 1 || loops[0].From != 0x204 || loops[0].To != 0x200 || loops[0].Count != 10 {
+		t.Errorf("Expected a loop from 0x204 to 0x200 taken 10 times, got %v", loops)
+	}
+
+	out := bytes.Buffer{}
+	_ = p.WriteFolded(&out)
+	if out.String() != "main 30\nmain;sub_206 20\n" {
+		t.Errorf("Unexpected folded stacks:\n%s", out.String())
+	}
+
+	out.Reset()
+	p.WriteReport(&out, 5)
+	if !strings.Contains(out.String(), "sub_206") {
+		t.Errorf("Expected report to list sub_206:\n%s", out.String())
+	}
+}
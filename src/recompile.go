@@ -0,0 +1,42 @@
+package main
+
+import (
+	"chip8/src/recompile"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// recompileRom implements `chip8 recompile [-o file.go] rom.ch8`. The Go file
+// is written next to the rom unless -o is given.
+func recompileRom(args []string) int {
+	flags := flag.NewFlagSet("recompile", flag.ExitOnError)
+	romFlag := flags.String("rom", "", "rom to recompile, instead of the positional argument")
+	output := flags.String("o", "", "Go file to write, defaults to the rom path with a .go extension")
+	pkg := flags.String("package", "recompiled", "package of the Go file")
+	_ = flags.Parse(args)
+
+	path, rom, err := readRom(flags, *romFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	source, err := recompile.Generate(rom, *pkg, filepath.Base(path))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+		return 2
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(path, filepath.Ext(path)) + ".go"
+	}
+	if err := ioutil.WriteFile(*output, source, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
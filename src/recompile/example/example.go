@@ -0,0 +1,504 @@
+// Code generated by chip8 recompile from example.ch8. DO NOT EDIT.
+
+package example
+
+import (
+	"bytes"
+	"chip8/src/chip8"
+)
+
+// Rom is the program this file was recompiled from, it is loaded at 0x200
+var Rom = []byte{
+	0xf0, 0x0a, 0x00, 0xe0, 0x68, 0x20, 0x69, 0x10, 0x22, 0x32, 0xc0, 0x03, 0x80, 0x0e, 0xb2, 0x10,
+	0x12, 0x18, 0x12, 0x1c, 0x12, 0x20, 0x12, 0x24, 0x78, 0xff, 0x12, 0x26, 0x78, 0x01, 0x12, 0x26,
+	0x79, 0xff, 0x12, 0x26, 0x79, 0x01, 0xa2, 0x56, 0xd8, 0x91, 0x62, 0x05, 0xe2, 0xa1, 0x22, 0x3e,
+	0x12, 0x08, 0x62, 0x02, 0xf2, 0x15, 0xf2, 0x07, 0x32, 0x00, 0x12, 0x36, 0x00, 0xee, 0x75, 0x01,
+	0x60, 0x75, 0x61, 0x02, 0xa2, 0x3e, 0xf1, 0x55, 0xa2, 0x57, 0xf5, 0x33, 0xf2, 0x65, 0xf2, 0x29,
+	0x63, 0x00, 0xd3, 0x35, 0x00, 0xee, 0x80, 0x00, 0x00, 0x00,
+}
+
+// Run runs n instructions like n calls of c.Step. Recompiled code is used
+// where the code in memory is still that of Rom, the rest is left to the
+// interpreter. Tracers of c are not told about recompiled instructions. It
+// returns false if the program counter left memory.
+func Run(c *chip8.Cpu, n int) bool {
+	for n > 0 {
+		if int(c.PC)+1 >= len(c.Memory) {
+			return false
+		}
+
+		done := 0
+		if int(c.PC) < len(entries) {
+			if e := entries[c.PC]; e.run != nil && unchanged(c, e.end) {
+				done = e.run(c, n)
+			}
+		}
+		if done == 0 {
+			c.Step()
+			done = 1
+		}
+		n -= done
+	}
+	return true
+}
+
+// entry is a recompiled block that contains an instruction. run runs at
+// most n instructions of the block from PC and returns how many it ran.
+type entry struct {
+	run func(c *chip8.Cpu, n int) int
+	end uint16
+}
+
+// unchanged reports whether the code from PC to end is still that of Rom
+func unchanged(c *chip8.Cpu, end uint16) bool {
+	return int(end) <= len(c.Memory) && bytes.Equal(c.Memory[c.PC:end], Rom[c.PC-0x200:end-0x200])
+}
+
+// entries has the block of every recompiled instruction and the end of that block
+var entries = [...]entry{
+	0x200: {block200, 0x202},
+	0x202: {block202, 0x20a},
+	0x204: {block202, 0x20a},
+	0x206: {block202, 0x20a},
+	0x208: {block202, 0x20a},
+	0x20a: {block20A, 0x210},
+	0x20c: {block20A, 0x210},
+	0x20e: {block20A, 0x210},
+	0x210: {block210, 0x212},
+	0x212: {block212, 0x214},
+	0x214: {block214, 0x216},
+	0x216: {block216, 0x218},
+	0x218: {block218, 0x21c},
+	0x21a: {block218, 0x21c},
+	0x21c: {block21C, 0x220},
+	0x21e: {block21C, 0x220},
+	0x220: {block220, 0x224},
+	0x222: {block220, 0x224},
+	0x224: {block224, 0x22e},
+	0x226: {block224, 0x22e},
+	0x228: {block224, 0x22e},
+	0x22a: {block224, 0x22e},
+	0x22c: {block224, 0x22e},
+	0x22e: {block22E, 0x230},
+	0x230: {block230, 0x232},
+	0x232: {block232, 0x23a},
+	0x234: {block232, 0x23a},
+	0x236: {block232, 0x23a},
+	0x238: {block232, 0x23a},
+	0x23a: {block23A, 0x23c},
+	0x23c: {block23C, 0x23e},
+	0x23e: {block23E, 0x248},
+	0x240: {block23E, 0x248},
+	0x242: {block23E, 0x248},
+	0x244: {block23E, 0x248},
+	0x246: {block23E, 0x248},
+	0x248: {block248, 0x24c},
+	0x24a: {block248, 0x24c},
+	0x24c: {block24C, 0x256},
+	0x24e: {block24C, 0x256},
+	0x250: {block24C, 0x256},
+	0x252: {block24C, 0x256},
+	0x254: {block24C, 0x256},
+}
+
+func block200(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x200: // LD V0, K
+		c.PC = 0x202
+		c.Execute(0xf0, 0x0a)
+		return done + 1
+	}
+	return done
+}
+
+func block202(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x202: // CLS
+		c.Execute(0x00, 0xe0)
+		done++
+		if done == n {
+			c.PC = 0x204
+			return done
+		}
+		fallthrough
+	case 0x204: // LD V8, 0x20
+		c.V[0x8] = 0x20
+		done++
+		if done == n {
+			c.PC = 0x206
+			return done
+		}
+		fallthrough
+	case 0x206: // LD V9, 0x10
+		c.V[0x9] = 0x10
+		done++
+		if done == n {
+			c.PC = 0x208
+			return done
+		}
+		fallthrough
+	case 0x208: // CALL 0x232
+		c.S[c.SP] = 0x20a
+		c.SP++
+		c.PC = 0x232
+		return done + 1
+	}
+	return done
+}
+
+func block20A(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x20a: // RND V0, 0x03
+		c.Execute(0xc0, 0x03)
+		done++
+		if done == n {
+			c.PC = 0x20c
+			return done
+		}
+		fallthrough
+	case 0x20c: // SHL V0, V0
+		v := c.V[0x0]
+		if c.Quirks.ShiftUsesVy {
+			v = c.V[0x0]
+		}
+		c.V[0x0] = v << 1
+		c.V[0xf] = v >> 7
+		done++
+		if done == n {
+			c.PC = 0x20e
+			return done
+		}
+		fallthrough
+	case 0x20e: // JP V0, 0x210
+		c.PC = 0x210
+		c.Execute(0xb2, 0x10)
+		return done + 1
+	}
+	return done
+}
+
+func block210(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x210: // JP 0x218
+		c.PC = 0x218
+		return done + 1
+	}
+	return done
+}
+
+func block212(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x212: // JP 0x21c
+		c.PC = 0x21c
+		return done + 1
+	}
+	return done
+}
+
+func block214(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x214: // JP 0x220
+		c.PC = 0x220
+		return done + 1
+	}
+	return done
+}
+
+func block216(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x216: // JP 0x224
+		c.PC = 0x224
+		return done + 1
+	}
+	return done
+}
+
+func block218(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x218: // ADD V8, 0xff
+		c.V[0x8] += 0xff
+		done++
+		if done == n {
+			c.PC = 0x21a
+			return done
+		}
+		fallthrough
+	case 0x21a: // JP 0x226
+		c.PC = 0x226
+		return done + 1
+	}
+	return done
+}
+
+func block21C(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x21c: // ADD V8, 0x01
+		c.V[0x8] += 0x01
+		done++
+		if done == n {
+			c.PC = 0x21e
+			return done
+		}
+		fallthrough
+	case 0x21e: // JP 0x226
+		c.PC = 0x226
+		return done + 1
+	}
+	return done
+}
+
+func block220(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x220: // ADD V9, 0xff
+		c.V[0x9] += 0xff
+		done++
+		if done == n {
+			c.PC = 0x222
+			return done
+		}
+		fallthrough
+	case 0x222: // JP 0x226
+		c.PC = 0x226
+		return done + 1
+	}
+	return done
+}
+
+func block224(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x224: // ADD V9, 0x01
+		c.V[0x9] += 0x01
+		done++
+		if done == n {
+			c.PC = 0x226
+			return done
+		}
+		fallthrough
+	case 0x226: // LD I, 0x256
+		c.I = 0x256
+		done++
+		if done == n {
+			c.PC = 0x228
+			return done
+		}
+		fallthrough
+	case 0x228: // DRW V8, V9, 1
+		c.Execute(0xd8, 0x91)
+		done++
+		if done == n {
+			c.PC = 0x22a
+			return done
+		}
+		fallthrough
+	case 0x22a: // LD V2, 0x05
+		c.V[0x2] = 0x05
+		done++
+		if done == n {
+			c.PC = 0x22c
+			return done
+		}
+		fallthrough
+	case 0x22c: // SKNP V2
+		c.PC = 0x22e
+		c.Execute(0xe2, 0xa1)
+		return done + 1
+	}
+	return done
+}
+
+func block22E(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x22e: // CALL 0x23e
+		c.S[c.SP] = 0x230
+		c.SP++
+		c.PC = 0x23e
+		return done + 1
+	}
+	return done
+}
+
+func block230(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x230: // JP 0x208
+		c.PC = 0x208
+		return done + 1
+	}
+	return done
+}
+
+func block232(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x232: // LD V2, 0x02
+		c.V[0x2] = 0x02
+		done++
+		if done == n {
+			c.PC = 0x234
+			return done
+		}
+		fallthrough
+	case 0x234: // LD DT, V2
+		c.DT = c.V[0x2]
+		done++
+		if done == n {
+			c.PC = 0x236
+			return done
+		}
+		fallthrough
+	case 0x236: // LD V2, DT
+		c.V[0x2] = c.DT
+		done++
+		if done == n {
+			c.PC = 0x238
+			return done
+		}
+		fallthrough
+	case 0x238: // SE V2, 0x00
+		if c.V[0x2] == 0x00 {
+			c.PC = 0x23c
+		} else {
+			c.PC = 0x23a
+		}
+		return done + 1
+	}
+	return done
+}
+
+func block23A(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x23a: // JP 0x236
+		c.PC = 0x236
+		return done + 1
+	}
+	return done
+}
+
+func block23C(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x23c: // RET
+		c.SP--
+		c.PC = c.S[c.SP]
+		return done + 1
+	}
+	return done
+}
+
+func block23E(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x23e: // ADD V5, 0x01
+		c.V[0x5] += 0x01
+		done++
+		if done == n {
+			c.PC = 0x240
+			return done
+		}
+		fallthrough
+	case 0x240: // LD V0, 0x75
+		c.V[0x0] = 0x75
+		done++
+		if done == n {
+			c.PC = 0x242
+			return done
+		}
+		fallthrough
+	case 0x242: // LD V1, 0x02
+		c.V[0x1] = 0x02
+		done++
+		if done == n {
+			c.PC = 0x244
+			return done
+		}
+		fallthrough
+	case 0x244: // LD I, 0x23e
+		c.I = 0x23e
+		done++
+		if done == n {
+			c.PC = 0x246
+			return done
+		}
+		fallthrough
+	case 0x246: // LD [I], V1
+		c.PC = 0x248
+		c.Execute(0xf1, 0x55)
+		return done + 1
+	}
+	return done
+}
+
+func block248(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x248: // LD I, 0x257
+		c.I = 0x257
+		done++
+		if done == n {
+			c.PC = 0x24a
+			return done
+		}
+		fallthrough
+	case 0x24a: // LD B, V5
+		c.PC = 0x24c
+		c.Execute(0xf5, 0x33)
+		return done + 1
+	}
+	return done
+}
+
+func block24C(c *chip8.Cpu, n int) int {
+	done := 0
+	switch c.PC {
+	case 0x24c: // LD V2, [I]
+		c.Execute(0xf2, 0x65)
+		done++
+		if done == n {
+			c.PC = 0x24e
+			return done
+		}
+		fallthrough
+	case 0x24e: // LD F, V2
+		c.I = uint16(c.V[0x2]) * 5
+		done++
+		if done == n {
+			c.PC = 0x250
+			return done
+		}
+		fallthrough
+	case 0x250: // LD V3, 0x00
+		c.V[0x3] = 0x00
+		done++
+		if done == n {
+			c.PC = 0x252
+			return done
+		}
+		fallthrough
+	case 0x252: // DRW V3, V3, 5
+		c.Execute(0xd3, 0x35)
+		done++
+		if done == n {
+			c.PC = 0x254
+			return done
+		}
+		fallthrough
+	case 0x254: // RET
+		c.SP--
+		c.PC = c.S[c.SP]
+		return done + 1
+	}
+	return done
+}
@@ -0,0 +1,70 @@
+package example
+
+import (
+	"bytes"
+	"chip8/src/chip8"
+	"chip8/src/headless"
+	"testing"
+)
+
+// newCpu loads Rom into a cpu that presses key 1 in frame 2 and holds key 5
+// twice for long enough to be seen at any speed
+func newCpu(t *testing.T) (*chip8.Cpu, *chip8.Framebuffer, *headless.ScriptedKeyboard) {
+	keys, err := headless.ParseKeyScript("2:1,3:,60:5,100:,200:5,240:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	display := &chip8.Framebuffer{}
+	keyboard := &headless.ScriptedKeyboard{Events: keys}
+	cpu := chip8.NewCPU(chip8.DefaultMemorySize, display, keyboard)
+	cpu.SetRng(chip8.NewSeededRng(7))
+	if err := cpu.LoadCode(bytes.NewReader(Rom), 0x200); err != nil {
+		t.Fatal(err)
+	}
+	return &cpu, display, keyboard
+}
+
+// countingTracer counts the instructions run by Step
+type countingTracer int
+
+func (c *countingTracer) Trace(chip8.TraceEntry) {
+	*c++
+}
+
+func TestRun_SameAsInterpreter(t *testing.T) {
+	for _, ipf := range []int{1, 7, 10} {
+		interpreted, interpretedScreen, interpretedKeys := newCpu(t)
+		recompiled, recompiledScreen, recompiledKeys := newCpu(t)
+		var interpretedSteps countingTracer
+		recompiled.SetTracer(&interpretedSteps)
+
+		for frame := uint64(0); frame < 300; frame++ {
+			interpretedKeys.SetFrame(frame)
+			recompiledKeys.SetFrame(frame)
+			for i := 0; i < ipf; i++ {
+				interpreted.Step()
+			}
+			if !Run(recompiled, ipf) {
+				t.Fatalf("%d instructions per frame: PC left memory in frame %d", ipf, frame)
+			}
+			interpreted.DecrementTimers()
+			recompiled.DecrementTimers()
+
+			if fields := chip8.CompareState(interpreted, recompiled); len(fields) > 0 {
+				t.Fatalf("%d instructions per frame: %v differ in frame %d", ipf, fields, frame)
+			}
+			if interpretedScreen.Pixels != recompiledScreen.Pixels {
+				t.Fatalf("%d instructions per frame: screens differ in frame %d", ipf, frame)
+			}
+		}
+
+		// Only waiting for a key and code after the rewrite need the interpreter
+		if total := 300 * ipf; int(interpretedSteps) > total/2 {
+			t.Errorf("%d instructions per frame: %d of %d instructions were interpreted", ipf, interpretedSteps, total)
+		}
+		// The first count adds 1 and rewrites the increment to 2
+		if v := recompiled.V[5]; v < 3 || v%2 == 0 {
+			t.Errorf("%d instructions per frame: expected the rewritten increment to count, V5 is %d", ipf, v)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package recompile
+
+import (
+	"bytes"
+	"chip8/src/chip8"
+	"fmt"
+	"go/format"
+	"sort"
+)
+
+// Origin is the address roms are loaded at
+const Origin = 0x200
+
+// block is a run of instructions where only the last one may jump, skip or
+// write to memory. Execution can enter it at any of its instructions.
+type block struct {
+	start, end int
+}
+
+// Generate translates the code of rom that can be reached from its start
+// into a Go file of package pkg. The file has a Run function that runs the
+// rom like calls of chip8.Cpu.Step and leaves computed jumps and code that
+// was changed in memory to the interpreter. name is the rom file mentioned
+// in the header.
+func Generate(rom []byte, pkg string, name string) ([]byte, error) {
+	if len(rom) == 0 {
+		return nil, fmt.Errorf("rom is empty")
+	}
+	if Origin+len(rom) > 0x1000 {
+		return nil, fmt.Errorf("rom is %d bytes long, at most %d fit into memory", len(rom), 0x1000-Origin)
+	}
+
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "// Code generated by chip8 recompile from %s. DO NOT EDIT.\n\n", name)
+	fmt.Fprintf(b, "package %s\n\n", pkg)
+	b.WriteString("import (\n\"bytes\"\n\"chip8/src/chip8\"\n)\n\n")
+
+	b.WriteString("// Rom is the program this file was recompiled from, it is loaded at 0x200\nvar Rom = []byte{")
+	for i, v := range rom {
+		if i%16 == 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(b, "0x%02x, ", v)
+	}
+	b.WriteString("\n}\n\n")
+	b.WriteString(runFunction)
+
+	blocks := findBlocks(rom)
+	b.WriteString("\n// entries has the block of every recompiled instruction and the end of that block\n")
+	b.WriteString("var entries = [...]entry{\n")
+	for _, bl := range blocks {
+		for a := bl.start; a < bl.end; a += 2 {
+			fmt.Fprintf(b, "0x%03x: {block%03X, 0x%03x},\n", a, bl.start, bl.end)
+		}
+	}
+	b.WriteString("}\n")
+
+	for _, bl := range blocks {
+		writeBlock(b, rom, bl)
+	}
+
+	return format.Source(b.Bytes())
+}
+
+// runFunction is the same in every generated file
+const runFunction = `// Run runs n instructions like n calls of c.Step. Recompiled code is used
+// where the code in memory is still that of Rom, the rest is left to the
+// interpreter. Tracers of c are not told about recompiled instructions. It
+// returns false if the program counter left memory.
+func Run(c *chip8.Cpu, n int) bool {
+	for n > 0 {
+		if int(c.PC)+1 >= len(c.Memory) {
+			return false
+		}
+
+		done := 0
+		if int(c.PC) < len(entries) {
+			if e := entries[c.PC]; e.run != nil && unchanged(c, e.end) {
+				done = e.run(c, n)
+			}
+		}
+		if done == 0 {
+			c.Step()
+			done = 1
+		}
+		n -= done
+	}
+	return true
+}
+
+// entry is a recompiled block that contains an instruction. run runs at
+// most n instructions of the block from PC and returns how many it ran.
+type entry struct {
+	run func(c *chip8.Cpu, n int) int
+	end uint16
+}
+
+// unchanged reports whether the code from PC to end is still that of Rom
+func unchanged(c *chip8.Cpu, end uint16) bool {
+	return int(end) <= len(c.Memory) && bytes.Equal(c.Memory[c.PC:end], Rom[c.PC-0x200:end-0x200])
+}
+`
+
+// opcode returns the instruction at address
+func opcode(rom []byte, address int) uint16 {
+	i := address - Origin
+	return uint16(rom[i])<<8 | uint16(rom[i+1])
+}
+
+// inRom reports whether a whole instruction at address lies within rom
+func inRom(rom []byte, address int) bool {
+	return address >= Origin && address+1 < Origin+len(rom)
+}
+
+// successors returns where execution can continue after the instruction at
+// address. The targets of computed jumps are unknown, but JP V0 usually
+// selects an entry of a table of jumps, which is followed.
+func successors(rom []byte, address int) []int {
+	op := opcode(rom, address)
+	next := address + 2
+	switch {
+	case op == 0x00EE:
+		return nil
+	case op>>12 == 0x1:
+		return []int{int(op & 0x0FFF)}
+	case op>>12 == 0x2:
+		return []int{int(op & 0x0FFF), next}
+	case op>>12 == 0x3, op>>12 == 0x4, op>>12 == 0x5, op>>12 == 0x9, op>>12 == 0xE:
+		return []int{next, next + 2}
+	case op>>12 == 0xB:
+		var table []int
+		for a := int(op & 0x0FFF); inRom(rom, a) && opcode(rom, a)>>12 == 0x1; a += 2 {
+			table = append(table, a)
+		}
+		return table
+	}
+	return []int{next}
+}
+
+// reachable follows every path from the start of rom and returns the
+// addresses of the instructions it finds, in order
+func reachable(rom []byte) []int {
+	found := map[int]bool{}
+	work := []int{Origin}
+	for len(work) > 0 {
+		a := work[len(work)-1]
+		work = work[:len(work)-1]
+		if found[a] || !inRom(rom, a) {
+			continue
+		}
+		found[a] = true
+		work = append(work, successors(rom, a)...)
+	}
+
+	addresses := make([]int, 0, len(found))
+	for a := range found {
+		addresses = append(addresses, a)
+	}
+	sort.Ints(addresses)
+	return addresses
+}
+
+// findBlocks groups the reachable instructions into blocks
+func findBlocks(rom []byte) []block {
+	addresses := reachable(rom)
+	found := map[int]bool{}
+	for _, a := range addresses {
+		found[a] = true
+	}
+
+	var blocks []block
+	assigned := map[int]bool{}
+	for _, a := range addresses {
+		if assigned[a] {
+			continue
+		}
+		end := a
+		for {
+			assigned[end] = true
+			_, ends := translate(opcode(rom, end), end+2)
+			end += 2
+			if ends || !found[end] || assigned[end] {
+				break
+			}
+		}
+		blocks = append(blocks, block{a, end})
+	}
+	return blocks
+}
+
+// writeBlock writes the function of a block. Every instruction is a case of
+// a switch on PC and falls through to the next one until n instructions ran.
+func writeBlock(b *bytes.Buffer, rom []byte, bl block) {
+	fmt.Fprintf(b, "\nfunc block%03X(c *chip8.Cpu, n int) int {\n", bl.start)
+	b.WriteString("done := 0\nswitch c.PC {\n")
+	for a := bl.start; a < bl.end; a += 2 {
+		op := opcode(rom, a)
+		code, ends := translate(op, a+2)
+		fmt.Fprintf(b, "case 0x%03x: // %s\n%s\n", a, chip8.Disassemble(op), code)
+		switch {
+		case ends:
+			b.WriteString("return done + 1\n")
+		case a+2 == bl.end:
+			fmt.Fprintf(b, "c.PC = 0x%03x\nreturn done + 1\n", a+2)
+		default:
+			fmt.Fprintf(b, "done++\nif done == n {\nc.PC = 0x%03x\nreturn done\n}\nfallthrough\n", a+2)
+		}
+	}
+	b.WriteString("}\nreturn done\n}\n")
+}
+
+// translate returns Go statements that run op, next is the address after it.
+// If ends is set the statements also set PC and the block has to end, as op
+// jumps, skips, writes to memory or is left to the interpreter, which may do
+// any of these.
+func translate(op uint16, next int) (code string, ends bool) {
+	x, y, n := op>>8&0xF, op>>4&0xF, op&0xF
+	kk, nnn := op&0xFF, op&0xFFF
+	vx, vy := fmt.Sprintf("c.V[%#x]", x), fmt.Sprintf("c.V[%#x]", y)
+	skip := func(condition string) (string, bool) {
+		return fmt.Sprintf("if %s {\nc.PC = 0x%03x\n} else {\nc.PC = 0x%03x\n}", condition, next+2, next), true
+	}
+	// execute leaves op to the interpreter
+	execute := fmt.Sprintf("c.Execute(0x%02x, 0x%02x)", op>>8, op&0xFF)
+	interpreted := fmt.Sprintf("c.PC = 0x%03x\n%s", next, execute)
+
+	switch op >> 12 {
+	case 0x0:
+		switch op {
+		case 0x00E0:
+			return execute, false
+		case 0x00EE:
+			return "c.SP--\nc.PC = c.S[c.SP]", true
+		}
+	case 0x1:
+		return fmt.Sprintf("c.PC = 0x%03x", nnn), true
+	case 0x2:
+		return fmt.Sprintf("c.S[c.SP] = 0x%03x\nc.SP++\nc.PC = 0x%03x", next, nnn), true
+	case 0x3:
+		return skip(fmt.Sprintf("%s == 0x%02x", vx, kk))
+	case 0x4:
+		return skip(fmt.Sprintf("%s != 0x%02x", vx, kk))
+	case 0x5:
+		return skip(fmt.Sprintf("%s == %s", vx, vy))
+	case 0x6:
+		return fmt.Sprintf("%s = 0x%02x", vx, kk), false
+	case 0x7:
+		return fmt.Sprintf("%s += 0x%02x", vx, kk), false
+	case 0x8:
+		if code, ok := arithmetic(vx, vy, n, x == y); ok {
+			return code, false
+		}
+	case 0x9:
+		return skip(fmt.Sprintf("%s != %s", vx, vy))
+	case 0xA:
+		return fmt.Sprintf("c.I = 0x%03x", nnn), false
+	case 0xC, 0xD:
+		return execute, false
+	case 0xF:
+		switch kk {
+		case 0x07:
+			return fmt.Sprintf("%s = c.DT", vx), false
+		case 0x15:
+			return fmt.Sprintf("c.DT = %s", vx), false
+		case 0x18:
+			return fmt.Sprintf("c.ST = %s", vx), false
+		case 0x1E:
+			return fmt.Sprintf("c.I += uint16(%s)", vx), false
+		case 0x29:
+			return fmt.Sprintf("c.I = uint16(%s) * 5", vx), false
+		case 0x65:
+			return execute, false
+		}
+	}
+	return interpreted, true
+}
+
+// arithmetic translates 8xyn, it returns false for undefined values of n
+func arithmetic(vx string, vy string, n uint16, same bool) (string, bool) {
+	switch n {
+	case 0x0:
+		if same {
+			return "// nothing to do", true
+		}
+		return fmt.Sprintf("%s = %s", vx, vy), true
+	case 0x1, 0x2, 0x3:
+		operator := map[uint16]string{0x1: "|=", 0x2: "&=", 0x3: "^="}[n]
+		return fmt.Sprintf("%s %s %s\nif c.Quirks.VFReset {\nc.V[0xf] = 0\n}", vx, operator, vy), true
+	case 0x4:
+		return fmt.Sprintf("r := uint16(%s) + uint16(%s)\n%s = uint8(r)\nc.V[0xf] = uint8(r >> 8)", vx, vy, vx), true
+	case 0x5, 0x7:
+		a, b := vx, vy
+		if n == 0x7 {
+			a, b = vy, vx
+		}
+		return fmt.Sprintf("a, b := %s, %s\n%s = a - b\nif a >= b {\nc.V[0xf] = 1\n} else {\nc.V[0xf] = 0\n}", a, b, vx), true
+	case 0x6:
+		return fmt.Sprintf("v := %s\nif c.Quirks.ShiftUsesVy {\nv = %s\n}\n%s = v >> 1\nc.V[0xf] = v & 0x01", vx, vy, vx), true
+	case 0xE:
+		return fmt.Sprintf("v := %s\nif c.Quirks.ShiftUsesVy {\nv = %s\n}\n%s = v << 1\nc.V[0xf] = v >> 7", vx, vy, vx), true
+	}
+	return "", false
+}
@@ -0,0 +1,95 @@
+package recompile
+
+import (
+	"bytes"
+	"chip8/src/asm"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerate_Example(t *testing.T) {
+	source, err := os.Open("testdata/example.asm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	rom, err := asm.Assemble(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generated, err := Generate(rom, "example", "example.ch8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ioutil.ReadFile("example/example.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(generated, expected) {
+		t.Errorf("example/example.go is outdated, regenerate it with chip8 recompile -package example -o src/recompile/example/example.go")
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	if _, err := Generate(nil, "p", "empty.ch8"); err == nil {
+		t.Errorf("Expected an error for an empty rom")
+	}
+	if _, err := Generate(make([]byte, 0xE01), "p", "large.ch8"); err == nil {
+		t.Errorf("Expected an error for a rom that does not fit into memory")
+	}
+}
+
+func TestReachable(t *testing.T) {
+	source := `
+	CALL sub
+	JP V0, table
+table:	JP one
+	JP two
+	DB 0xFF, 0xFF
+one:	JP one
+two:	JP two
+sub:	SE V1, 0
+	LD V2, 1
+	RET
+	DB 0xFF, 0xFF
+`
+	rom, err := asm.Assemble(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []int{0x200, 0x202, 0x204, 0x206, 0x20A, 0x20C, 0x20E, 0x210, 0x212}
+	if addresses := reachable(rom); !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("Expected %x, got %x", expected, addresses)
+	}
+
+	blocks := []block{{0x200, 0x202}, {0x202, 0x204}, {0x204, 0x206}, {0x206, 0x208}, {0x20A, 0x20C}, {0x20C, 0x20E}, {0x20E, 0x210}, {0x210, 0x214}}
+	if found := findBlocks(rom); !reflect.DeepEqual(found, blocks) {
+		t.Errorf("Expected blocks %x, got %x", blocks, found)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		op   uint16
+		code string
+		ends bool
+	}{
+		{0x6A12, "c.V[0xa] = 0x12", false},
+		{0x1234, "c.PC = 0x234", true},
+		{0xD125, "c.Execute(0xd1, 0x25)", false},
+		{0xF255, "c.PC = 0x302\nc.Execute(0xf2, 0x55)", true},
+		{0xF30A, "c.PC = 0x302\nc.Execute(0xf3, 0x0a)", true},
+		{0x8128, "c.PC = 0x302\nc.Execute(0x81, 0x28)", true},
+	}
+	for _, test := range tests {
+		code, ends := translate(test.op, 0x302)
+		if code != test.code || ends != test.ends {
+			t.Errorf("%04X: expected %q %v, got %q %v", test.op, test.code, test.ends, code, ends)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+; The rom of package example. It waits for a key, then moves a dot in random
+; directions through a jump table, waits on the delay timer between moves
+; and counts presses of key 5 with an increment that rewrites itself.
+
+start:	LD V0, K
+	CLS
+	LD V8, 32
+	LD V9, 16
+main:	CALL wait
+	RND V0, 0x03
+	SHL V0
+	JP V0, table
+table:	JP left
+	JP right
+	JP up
+	JP down
+left:	ADD V8, 0xFF
+	JP moved
+right:	ADD V8, 1
+	JP moved
+up:	ADD V9, 0xFF
+	JP moved
+down:	ADD V9, 1
+moved:	LD I, dot
+	DRW V8, V9, 1
+	LD V2, 5
+	SKNP V2
+	CALL count
+	JP main
+
+wait:	LD V2, 2
+	LD DT, V2
+delay:	LD V2, DT
+	SE V2, 0
+	JP delay
+	RET
+
+; The first press adds 1, every later one 2
+count:	ADD V5, 1
+	LD V0, 0x75
+	LD V1, 2
+	LD I, count
+	LD [I], V1
+	LD I, digits
+	LD B, V5
+	LD V2, [I]
+	LD F, V2
+	LD V3, 0
+	DRW V3, V3, 5
+	RET
+
+dot:	DB 0x80
+digits:	DB 0, 0, 0
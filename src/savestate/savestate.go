@@ -0,0 +1,164 @@
+package savestate
+
+import (
+	"bytes"
+	"chip8/src/chip8"
+	"chip8/src/headless"
+	"chip8/src/machine"
+	"chip8/src/movie"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Version is the version of the file format written by Write
+const Version = 1
+
+// ThumbnailScale is the size of a screen pixel in thumbnails
+const ThumbnailScale = 2
+
+// State is a machine snapshot together with what it was taken of
+type State struct {
+	RomHash  string
+	RomName  string
+	Saved    time.Time
+	Snapshot machine.Snapshot
+	// Thumbnail is a PNG of the screen
+	Thumbnail []byte
+}
+
+// New wraps a snapshot of the rom with the given hash and name and renders its thumbnail
+func New(romHash string, romName string, s machine.Snapshot, saved time.Time) (*State, error) {
+	fb := &chip8.Framebuffer{Pixels: s.Pixels}
+	thumbnail := bytes.Buffer{}
+	if err := headless.WritePNG(&thumbnail, fb, ThumbnailScale); err != nil {
+		return nil, err
+	}
+
+	return &State{
+		RomHash:   romHash,
+		RomName:   romName,
+		Saved:     saved,
+		Snapshot:  s,
+		Thumbnail: thumbnail.Bytes(),
+	}, nil
+}
+
+// jsonState is the on disk format. The screen is stored as rows of '#' and
+// '.' so that it can be read in the file.
+type jsonState struct {
+	Version     int         `json:"version"`
+	RomHash     string      `json:"rom_hash"`
+	RomName     string      `json:"rom_name"`
+	Saved       time.Time   `json:"saved"`
+	Frame       uint64      `json:"frame"`
+	Step        int         `json:"step"`
+	V           [0x10]uint8 `json:"v"`
+	PC          uint16      `json:"pc"`
+	I           uint16      `json:"i"`
+	SP          uint16      `json:"sp"`
+	Stack       [16]uint16  `json:"stack"`
+	DT          uint8       `json:"dt"`
+	ST          uint8       `json:"st"`
+	Waiting     bool        `json:"waiting"`
+	WaitPressed bool        `json:"wait_pressed"`
+	WaitKey     uint8       `json:"wait_key"`
+	Rng         uint64      `json:"rng"`
+	Memory      []byte      `json:"memory"`
+	Screen      []string    `json:"screen"`
+	Thumbnail   []byte      `json:"thumbnail"`
+}
+
+// Write stores the state as JSON
+func (s *State) Write(w io.Writer) error {
+	cpu := s.Snapshot.Cpu
+	j := jsonState{
+		Version:     Version,
+		RomHash:     s.RomHash,
+		RomName:     s.RomName,
+		Saved:       s.Saved,
+		Frame:       s.Snapshot.Frame,
+		Step:        s.Snapshot.Step,
+		V:           cpu.V,
+		PC:          cpu.PC,
+		I:           cpu.I,
+		SP:          cpu.SP,
+		Stack:       cpu.S,
+		DT:          cpu.DT,
+		ST:          cpu.ST,
+		Waiting:     cpu.Waiting,
+		WaitPressed: cpu.WaitPressed,
+		WaitKey:     cpu.WaitKey,
+		Rng:         cpu.Rng,
+		Memory:      cpu.Memory,
+		Thumbnail:   s.Thumbnail,
+	}
+	for y := 0; y < chip8.ScreenHeight; y++ {
+		row := make([]byte, chip8.ScreenWidth)
+		for x := range row {
+			row[x] = '.'
+			if s.Snapshot.Pixels[y*chip8.ScreenWidth+x] {
+				row[x] = '#'
+			}
+		}
+		j.Screen = append(j.Screen, string(row))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(j)
+}
+
+// Read loads a state written by Write
+func Read(r io.Reader) (*State, error) {
+	var j jsonState
+	if err := json.NewDecoder(r).Decode(&j); err != nil {
+		return nil, err
+	}
+	if j.Version != Version {
+		return nil, fmt.Errorf("unsupported save state version %d", j.Version)
+	}
+	// The hash names the directory of the state in a Store
+	if !movie.IsRomHash(j.RomHash) {
+		return nil, fmt.Errorf("rom_hash %q is not a sha256 hash", j.RomHash)
+	}
+	if len(j.Screen) != chip8.ScreenHeight {
+		return nil, fmt.Errorf("screen has %d rows instead of %d", len(j.Screen), chip8.ScreenHeight)
+	}
+
+	s := &State{
+		RomHash:   j.RomHash,
+		RomName:   j.RomName,
+		Saved:     j.Saved,
+		Thumbnail: j.Thumbnail,
+		Snapshot: machine.Snapshot{
+			Cpu: chip8.State{
+				Memory:      j.Memory,
+				V:           j.V,
+				PC:          j.PC,
+				SP:          j.SP,
+				S:           j.Stack,
+				I:           j.I,
+				DT:          j.DT,
+				ST:          j.ST,
+				Waiting:     j.Waiting,
+				WaitPressed: j.WaitPressed,
+				WaitKey:     j.WaitKey,
+				Rng:         j.Rng,
+			},
+			Frame: j.Frame,
+			Step:  j.Step,
+		},
+	}
+	for y, row := range j.Screen {
+		if len(row) != chip8.ScreenWidth || strings.Trim(row, "#.") != "" {
+			return nil, fmt.Errorf("screen row %d is not %d characters of # and .", y, chip8.ScreenWidth)
+		}
+		for x, c := range row {
+			s.Snapshot.Pixels[y*chip8.ScreenWidth+x] = c == '#'
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,142 @@
+package savestate
+
+import (
+	"bytes"
+	"chip8/src/chip8"
+	"chip8/src/machine"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const hash = "8b6cc525d64351cda2bc6f223baf237b2af808b825b5f3ccdd90ab8df10b84d1"
+
+func snapshot() machine.Snapshot {
+	cpu := chip8.NewCPU(0x300, nil, nil)
+	cpu.SetRng(chip8.NewSeededRng(3))
+	cpu.V[5], cpu.PC, cpu.DT, cpu.S[0], cpu.SP = 7, 0x24A, 30, 0x206, 1
+	cpu.Memory[0x2FF] = 0xAB
+
+	s := machine.Snapshot{Cpu: cpu.Snapshot(), Frame: 1234, Step: 3}
+	s.Pixels[0], s.Pixels[chip8.ScreenWidth+1] = true, true
+	return s
+}
+
+func TestState_WriteRead(t *testing.T) {
+	saved := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	state, err := New(hash, "BLINKY", snapshot(), saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := bytes.Buffer{}
+	if err := state.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, state) {
+		t.Errorf("Read returned %+v, want %+v", got, state)
+	}
+
+	img, err := png.Decode(bytes.NewReader(got.Thumbnail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != chip8.ScreenWidth*ThumbnailScale || b.Dy() != chip8.ScreenHeight*ThumbnailScale {
+		t.Errorf("thumbnail is %v", b)
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := Store{Dir: t.TempDir()}
+	state, _ := New(hash, "BLINKY", snapshot(), time.Now())
+
+	if _, err := store.Load(hash, 2); !os.IsNotExist(err) {
+		t.Errorf("loading an empty slot returned %v", err)
+	}
+
+	for _, slot := range []int{3, 2} {
+		if err := store.Save(state, slot); err != nil {
+			t.Fatal(err)
+		}
+	}
+	loaded, err := store.Load(hash, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Snapshot.Frame != 1234 {
+		t.Errorf("loaded frame %d", loaded.Snapshot.Frame)
+	}
+
+	slots, err := store.List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slots) != 2 || slots[0].Number != 2 || slots[1].Number != 3 || slots[0].RomHash != hash {
+		t.Errorf("List returned %+v", slots)
+	}
+
+	if err := store.Remove(hash, 2); err != nil {
+		t.Fatal(err)
+	}
+	if slots, _ := store.List(hash); len(slots) != 1 || slots[0].Number != 3 {
+		t.Errorf("after removing slot 2 List returned %+v", slots)
+	}
+
+	if err := store.Save(state, 0); err == nil {
+		t.Error("saving into slot 0 succeeded")
+	}
+}
+
+func TestStore_ListBrokenState(t *testing.T) {
+	store := Store{Dir: t.TempDir()}
+	state, _ := New(hash, "BLINKY", snapshot(), time.Now())
+	if err := store.Save(state, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(store.Dir, hash, "2.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	slots, err := store.List(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slots) != 2 || slots[0].Err != nil || slots[0].State == nil || slots[1].Err == nil {
+		t.Errorf("Expected slot 1 to load and slot 2 to carry an error, got %+v", slots)
+	}
+}
+
+// A shared state must not be able to name a directory outside the store
+func TestStore_InvalidRomHash(t *testing.T) {
+	dir := t.TempDir()
+	store := Store{Dir: filepath.Join(dir, "states")}
+	state, _ := New(hash, "BLINKY", snapshot(), time.Now())
+	b := bytes.Buffer{}
+	if err := state.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	shared := bytes.Replace(b.Bytes(), []byte(hash), []byte("../../escaped"), 1)
+	if _, err := Read(bytes.NewReader(shared)); err == nil {
+		t.Error("Read accepted the rom hash ../../escaped")
+	}
+
+	state.RomHash = "../escaped"
+	if err := store.Save(state, 1); err == nil {
+		t.Error("Save accepted the rom hash ../escaped")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped")); !os.IsNotExist(err) {
+		t.Errorf("Save created a directory outside the store: %v", err)
+	}
+	if _, err := store.Load("..", 1); err == nil {
+		t.Error("Load accepted the rom hash ..")
+	}
+}
@@ -0,0 +1,161 @@
+package savestate
+
+import (
+	"chip8/src/movie"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Store keeps numbered slots per rom as Dir/rom hash/slot.json
+type Store struct {
+	Dir string
+}
+
+// Slot is a saved state as listed by Store.List
+type Slot struct {
+	RomHash string
+	Number  int
+	State   *State
+	// Err is set instead of State if the file could not be read
+	Err error
+}
+
+// DefaultDir is the save state directory in the user data directory, for
+// example ~/.local/share/chip8/states on Linux
+func DefaultDir() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		switch runtime.GOOS {
+		case "windows":
+			dir = os.Getenv("LocalAppData")
+		case "darwin", "ios":
+			var err error
+			if dir, err = os.UserConfigDir(); err != nil {
+				return "", err
+			}
+		default:
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			dir = filepath.Join(home, ".local", "share")
+		}
+	}
+	if dir == "" {
+		return "", fmt.Errorf("no user data directory")
+	}
+	return filepath.Join(dir, "chip8", "states"), nil
+}
+
+// path returns the file of a slot. Anything but a hash could lead out of Dir.
+func (s Store) path(romHash string, slot int) (string, error) {
+	if !movie.IsRomHash(romHash) {
+		return "", fmt.Errorf("invalid rom hash %q", romHash)
+	}
+	return filepath.Join(s.Dir, romHash, strconv.Itoa(slot)+".json"), nil
+}
+
+// Save writes state into a slot of its rom, replacing what was there
+func (s Store) Save(state *State, slot int) error {
+	if slot < 1 {
+		return fmt.Errorf("invalid slot %d", slot)
+	}
+	path, err := s.path(state.RomHash, slot)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// A state that failed to write must not replace the old one
+	f, err := ioutil.TempFile(filepath.Dir(path), ".save-*")
+	if err != nil {
+		return err
+	}
+	if err := state.Write(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Load reads a slot of a rom. The error satisfies os.IsNotExist if the slot is empty.
+func (s Store) Load(romHash string, slot int) (*State, error) {
+	path, err := s.path(romHash, slot)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	state, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", f.Name(), err)
+	}
+	return state, nil
+}
+
+// Remove empties a slot
+func (s Store) Remove(romHash string, slot int) error {
+	path, err := s.path(romHash, slot)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	// The rom directory goes once its last slot is removed
+	_ = os.Remove(filepath.Join(s.Dir, romHash))
+	return nil
+}
+
+// List returns the slots of a rom, or of all roms if romHash is empty,
+// ordered by rom and slot number. Files that cannot be read are listed with
+// their error, so that one broken state does not hide the others.
+func (s Store) List(romHash string) ([]Slot, error) {
+	pattern := filepath.Join(s.Dir, "*", "*.json")
+	if romHash != "" {
+		if !movie.IsRomHash(romHash) {
+			return nil, fmt.Errorf("invalid rom hash %q", romHash)
+		}
+		pattern = filepath.Join(s.Dir, romHash, "*.json")
+	}
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var slots []Slot
+	for _, path := range paths {
+		number, err := strconv.Atoi(strings.TrimSuffix(filepath.Base(path), ".json"))
+		hash := filepath.Base(filepath.Dir(path))
+		if err != nil || number < 1 || !movie.IsRomHash(hash) {
+			continue
+		}
+		state, err := s.Load(hash, number)
+		slots = append(slots, Slot{RomHash: hash, Number: number, State: state, Err: err})
+	}
+
+	sort.Slice(slots, func(i, j int) bool {
+		if slots[i].RomHash != slots[j].RomHash {
+			return slots[i].RomHash < slots[j].RomHash
+		}
+		return slots[i].Number < slots[j].Number
+	})
+	return slots, nil
+}
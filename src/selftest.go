@@ -0,0 +1,63 @@
+package main
+
+import (
+	"chip8/src/headless"
+	"chip8/src/selftest"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// runSelftest implements `chip8 selftest`. It returns 0 when every embedded
+// rom produced its golden framebuffer, 1 on mismatches and 2 on errors.
+func runSelftest(args []string) int {
+	flags := flag.NewFlagSet("selftest", flag.ExitOnError)
+	verbose := flags.Bool("v", false, "print the framebuffer hash of every run")
+	screenshots := flags.String("screenshots", "", "write the final frame of every run as a PNG into this directory")
+	_ = flags.Parse(args)
+
+	results, err := selftest.RunAll()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	failed := 0
+	for _, r := range results {
+		status := "PASS"
+		if !r.Passed() {
+			status = "FAIL"
+			failed++
+		}
+
+		if *verbose {
+			fmt.Printf("%s  %-14s %-8s %s\n", status, r.Case, r.Profile, r.Hash)
+		} else {
+			fmt.Printf("%s  %-14s %s\n", status, r.Case, r.Profile)
+		}
+
+		if *screenshots != "" {
+			if err := writeScreenshot(filepath.Join(*screenshots, r.Case+"-"+r.Profile+".png"), r); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+		}
+	}
+
+	fmt.Printf("%d/%d passed\n", len(results)-failed, len(results))
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func writeScreenshot(path string, r selftest.Result) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return headless.WritePNG(f, r.Display, 8)
+}
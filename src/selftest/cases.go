@@ -0,0 +1,88 @@
+package selftest
+
+// Cases are the embedded conformance roms, see roms/README.md. The golden
+// hashes are those of the screenshots in testdata, see TestCases_MatchReference.
+var Cases = []Case{
+	{
+		Name:   "ibm-logo",
+		Rom:    "ibm-logo.ch8",
+		Frames: 10,
+		Golden: map[string]string{
+			"cosmac":  "b12be07c247d2a94b678808638361649ca8d14c22f1e1468c9849fd0aefa4421",
+			"default": "b12be07c247d2a94b678808638361649ca8d14c22f1e1468c9849fd0aefa4421",
+			"schip":   "b12be07c247d2a94b678808638361649ca8d14c22f1e1468c9849fd0aefa4421",
+		},
+	},
+	{
+		Name:   "corax+",
+		Rom:    "corax+.ch8",
+		Frames: 60,
+		Golden: map[string]string{
+			"cosmac":  "f7accf00a65c264fadfd94280d57f6c6564115df4b99316395e8253ff1729024",
+			"default": "f7accf00a65c264fadfd94280d57f6c6564115df4b99316395e8253ff1729024",
+			"schip":   "f7accf00a65c264fadfd94280d57f6c6564115df4b99316395e8253ff1729024",
+		},
+	},
+	{
+		Name:   "flags",
+		Rom:    "flags.ch8",
+		Frames: 120,
+		Golden: map[string]string{
+			"cosmac":  "c9b71cf8aa770baf37cbf0f85b2455fafdc1e537392a258cc3ac9af0ee17d771",
+			"default": "c9b71cf8aa770baf37cbf0f85b2455fafdc1e537392a258cc3ac9af0ee17d771",
+			"schip":   "c9b71cf8aa770baf37cbf0f85b2455fafdc1e537392a258cc3ac9af0ee17d771",
+		},
+	},
+	{
+		// The CHIP-8 platform expects every quirk of the cosmac profile and also
+		// waiting for the display and clipping, which are not emulated
+		Name:   "quirks",
+		Rom:    "quirks.ch8",
+		Frames: 600,
+		Select: 1,
+		Golden: map[string]string{
+			"cosmac":  "fcd4bd7fcdd593702f01e7599889ce777912f9c83b487fb5a9711aa1da154d3f",
+			"default": "ae7723f246f8969bb9e771c166863f5ba9395332d40c3418b88eb8a13db74721",
+			"schip":   "f79ed3a5e048d1b7a98c6c6eb14224c0430abcd29a1d19ff45c37195e9cc519f",
+		},
+	},
+	{
+		// Keys 1 and 6 are lit while they are held
+		Name:   "keypad-down",
+		Rom:    "keypad.ch8",
+		Frames: 200,
+		Select: 1,
+		Keys:   "10:16",
+		Golden: map[string]string{
+			"cosmac":  "7554e61f74fc93ba966cffb7d564b5675e046128ef6b0be26b3d50242032f268",
+			"default": "7554e61f74fc93ba966cffb7d564b5675e046128ef6b0be26b3d50242032f268",
+			"schip":   "7554e61f74fc93ba966cffb7d564b5675e046128ef6b0be26b3d50242032f268",
+		},
+	},
+	{
+		// Every key but 1 and 6 is lit while they are held
+		Name:   "keypad-up",
+		Rom:    "keypad.ch8",
+		Frames: 200,
+		Select: 2,
+		Keys:   "10:16",
+		Golden: map[string]string{
+			"cosmac":  "4315a6d4f252dd57e5084d15409c9dc3f1ad15c9332710ccf8b9060f8ef3639d",
+			"default": "4315a6d4f252dd57e5084d15409c9dc3f1ad15c9332710ccf8b9060f8ef3639d",
+			"schip":   "4315a6d4f252dd57e5084d15409c9dc3f1ad15c9332710ccf8b9060f8ef3639d",
+		},
+	},
+	{
+		// A press and release of key 5 answers Fx0A
+		Name:   "keypad-getkey",
+		Rom:    "keypad.ch8",
+		Frames: 120,
+		Select: 3,
+		Keys:   "30:5,60:",
+		Golden: map[string]string{
+			"cosmac":  "0f8016c455b8c768f369c9ebc6105d0eca926a50021d67afd3236cbd1ec0e625",
+			"default": "0f8016c455b8c768f369c9ebc6105d0eca926a50021d67afd3236cbd1ec0e625",
+			"schip":   "0f8016c455b8c768f369c9ebc6105d0eca926a50021d67afd3236cbd1ec0e625",
+		},
+	},
+}
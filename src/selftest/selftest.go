@@ -0,0 +1,125 @@
+package selftest
+
+import (
+	"bytes"
+	"chip8/src/chip8"
+	"chip8/src/headless"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+//go:embed roms/*.ch8
+var roms embed.FS
+
+// Case is a run of a conformance rom together with the screen it must end up showing
+type Case struct {
+	Name string
+	// Rom is the embedded rom to run
+	Rom    string
+	Frames uint64
+	Keys   string
+	// Select is stored at 0x1FF before the rom starts. The quirks and keypad
+	// roms read it to pick a platform or test instead of showing a menu.
+	Select uint8
+	// Golden maps quirk profile names to the expected FramebufferHash.
+	// Profiles that are not listed fall back to "default".
+	Golden map[string]string
+}
+
+// Result is the outcome of running one Case with one quirk profile
+type Result struct {
+	Case    string
+	Profile string
+	Hash    string
+	Want    string
+	Display *chip8.Framebuffer
+}
+
+func (r Result) Passed() bool {
+	return r.Hash == r.Want
+}
+
+// Run executes c headlessly with the quirks of profile
+func Run(c Case, profile string) (Result, error) {
+	rom, err := Rom(c.Rom)
+	if err != nil {
+		return Result{}, err
+	}
+
+	quirks, ok := chip8.QuirkProfile(profile)
+	if !ok {
+		return Result{}, fmt.Errorf("unknown quirk profile %q", profile)
+	}
+
+	events, err := headless.ParseKeyScript(c.Keys)
+	if err != nil {
+		return Result{}, fmt.Errorf("%s: %s", c.Name, err)
+	}
+
+	r := headless.NewRunner(chip8.DefaultMemorySize, events)
+	r.Cpu.Quirks = quirks
+	if err := r.Cpu.LoadProgram(bytes.NewReader(rom)); err != nil {
+		return Result{}, err
+	}
+	r.Cpu.Memory[0x1FF] = c.Select
+	r.RunFrames(c.Frames)
+
+	want, ok := c.Golden[profile]
+	if !ok {
+		want = c.Golden["default"]
+	}
+
+	return Result{
+		Case:    c.Name,
+		Profile: profile,
+		Hash:    FramebufferHash(r.Display),
+		Want:    want,
+		Display: r.Display,
+	}, nil
+}
+
+// RunAll runs every case in Cases with every quirk profile
+func RunAll() ([]Result, error) {
+	var results []Result
+	for _, c := range Cases {
+		for _, profile := range chip8.QuirkProfileNames() {
+			r, err := Run(c, profile)
+			if err != nil {
+				return results, err
+			}
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+// FramebufferHash returns the hex encoded sha256 of the lit pixels, one byte per pixel
+func FramebufferHash(f *chip8.Framebuffer) string {
+	pixels := make([]byte, len(f.Pixels))
+	for i, on := range f.Pixels {
+		if on {
+			pixels[i] = 1
+		}
+	}
+	sum := sha256.Sum256(pixels)
+	return hex.EncodeToString(sum[:])
+}
+
+// Rom returns the embedded rom called name
+func Rom(name string) ([]byte, error) {
+	return roms.ReadFile("roms/" + name)
+}
+
+// Roms lists the embedded rom names
+func Roms() []string {
+	entries, _ := roms.ReadDir("roms")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
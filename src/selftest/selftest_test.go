@@ -0,0 +1,133 @@
+package selftest
+
+import (
+	"chip8/src/chip8"
+	"image/png"
+	"os"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	results, err := RunAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range results {
+		if !r.Passed() {
+			t.Errorf("%s with %s quirks: expected framebuffer %s, got %s", r.Case, r.Profile, r.Want, r.Hash)
+		}
+	}
+}
+
+func TestCases_CoverEmbeddedRoms(t *testing.T) {
+	cases := map[string]bool{}
+	for _, c := range Cases {
+		cases[c.Rom] = true
+	}
+
+	for _, rom := range Roms() {
+		if !cases[rom] {
+			t.Errorf("Embedded rom %s has no test case", rom)
+		}
+	}
+}
+
+// The golden hashes must be those of the screenshots in testdata, which the
+// test suite took in Octo, so they do not just repeat what this emulator draws
+func TestCases_MatchReference(t *testing.T) {
+	for _, c := range Cases {
+		reference, err := referenceScreen("testdata/" + c.Name + ".png")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, profile := range chip8.QuirkProfileNames() {
+			want, ok := c.Golden[profile]
+			if !ok {
+				want = c.Golden["default"]
+			}
+
+			expected := reference
+			if c.Name == "quirks" {
+				quirks, _ := chip8.QuirkProfile(profile)
+				expected = quirksScreen(reference, quirks)
+			}
+			if hash := FramebufferHash(expected); hash != want {
+				t.Errorf("%s with %s quirks: golden is %s, the reference screen is %s", c.Name, profile, want, hash)
+			}
+		}
+	}
+}
+
+// referenceScreen reads a screenshot of the 64x32 screen scaled by any whole
+// factor. Octo draws lit pixels in yellow and the background in brown.
+func referenceScreen(path string) (*chip8.Framebuffer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+
+	screen := &chip8.Framebuffer{}
+	b := img.Bounds()
+	scale := b.Dx() / chip8.ScreenWidth
+	for y := 0; y < chip8.ScreenHeight; y++ {
+		for x := 0; x < chip8.ScreenWidth; x++ {
+			r, _, _, _ := img.At(b.Min.X+x*scale+scale/2, b.Min.Y+y*scale+scale/2).RGBA()
+			screen.Pixels[y*chip8.ScreenWidth+x] = r > 0xCC00
+		}
+	}
+	return screen, nil
+}
+
+// quirksScreen turns the screenshot of the quirks rom into the screen it shows
+// for q. The rom lists six quirks in rows of five pixels, each with ON or OFF
+// at x 42 and a check at x 59 if that is what the chosen platform, CHIP-8,
+// has. The screenshot shows a COSMAC VIP with every quirk as expected.
+func quirksScreen(reference *chip8.Framebuffer, q chip8.Quirks) *chip8.Framebuffer {
+	// vF reset, memory, display wait, clipping, shifting and jumping. Waiting
+	// for the display and clipping sprites are not emulated.
+	detected := []bool{q.VFReset, q.LoadStoreIncrementsI, false, false, !q.ShiftUsesVy, q.JumpUsesVx}
+	platform := []bool{true, true, true, true, false, false}
+
+	// The first row shows ON and the fifth OFF. The cross is flag-err of
+	// the rom's text-rendering.8o.
+	on, off := copyArea(reference, 42, 1, 14, 4), copyArea(reference, 42, 21, 14, 4)
+	check := copyArea(reference, 59, 1, 3, 4)
+	cross := [][]bool{{false, false, false}, {true, false, true}, {false, true, false}, {true, false, true}}
+
+	screen := *reference
+	for i := range detected {
+		y := 1 + 5*i
+		value, mark := off, cross
+		if detected[i] {
+			value = on
+		}
+		if detected[i] == platform[i] {
+			mark = check
+		}
+		pasteArea(&screen, 42, y, value)
+		pasteArea(&screen, 59, y, mark)
+	}
+	return &screen
+}
+
+func copyArea(f *chip8.Framebuffer, x int, y int, width int, height int) [][]bool {
+	area := make([][]bool, height)
+	for row := range area {
+		start := (y+row)*chip8.ScreenWidth + x
+		area[row] = append([]bool(nil), f.Pixels[start:start+width]...)
+	}
+	return area
+}
+
+func pasteArea(f *chip8.Framebuffer, x int, y int, area [][]bool) {
+	for row, pixels := range area {
+		copy(f.Pixels[(y+row)*chip8.ScreenWidth+x:], pixels)
+	}
+}
@@ -13,7 +13,7 @@ type TableDumper struct {
 }
 
 func (t TableDumper) DumpState(c chip8.Cpu) {
-	fmt.Println("")
+	fmt.Fprintln(t.To, "")
 	table := tablewriter.NewWriter(t.To)
 	table.SetAlignment(tablewriter.ALIGN_LEFT)
 	table.SetHeader([]string{"Subject", "Value(Hex)", "Value(int)", "RValue(Hex)", "RValue(int)"})
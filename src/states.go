@@ -0,0 +1,203 @@
+package main
+
+import (
+	"chip8/src/input"
+	"chip8/src/movie"
+	"chip8/src/savestate"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const statesUsage = `usage: chip8 states [-dir dir] ls [rom.ch8]
+       chip8 states [-dir dir] rm rom.ch8 slot...
+       chip8 states [-dir dir] export rom.ch8 slot file.json|file.png
+       chip8 states [-dir dir] import file.json [slot]
+`
+
+func defaultStatesDir() string {
+	dir, err := savestate.DefaultDir()
+	if err != nil {
+		return "states"
+	}
+	return dir
+}
+
+// runStates implements `chip8 states`, which manages the save state slots
+// written by the save state hotkeys
+func runStates(args []string) int {
+	flags := flag.NewFlagSet("states", flag.ExitOnError)
+	dir := flags.String("dir", defaultStatesDir(), "save state directory")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, statesUsage)
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	store := savestate.Store{Dir: *dir}
+	command, args := flags.Arg(0), flags.Args()[1:]
+
+	var err error
+	switch {
+	case command == "ls" && len(args) <= 1:
+		err = listStates(store, args)
+	case command == "rm" && len(args) >= 2:
+		err = removeStates(store, args[0], args[1:])
+	case command == "export" && len(args) == 3:
+		err = exportState(store, args[0], args[1], args[2])
+	case command == "import" && (len(args) == 1 || len(args) == 2):
+		err = importState(store, args)
+	default:
+		flags.Usage()
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func listStates(store savestate.Store, args []string) error {
+	hash := ""
+	if len(args) == 1 {
+		rom, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		hash = movie.RomHash(rom)
+	}
+
+	slots, err := store.List(hash)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%-24s %-12s %4s  %-19s %10s\n", "ROM", "HASH", "SLOT", "SAVED", "FRAME")
+	broken := 0
+	for _, s := range slots {
+		if s.Err != nil {
+			fmt.Fprintln(os.Stderr, s.Err)
+			broken++
+			continue
+		}
+		fmt.Printf("%-24s %-12s %4d  %-19s %10d\n",
+			s.State.RomName, s.RomHash[:12], s.Number, s.State.Saved.Local().Format("2006-01-02 15:04:05"), s.State.Snapshot.Frame)
+	}
+	if broken > 0 {
+		return fmt.Errorf("%d of %d states could not be read", broken, len(slots))
+	}
+	return nil
+}
+
+func removeStates(store savestate.Store, romPath string, slots []string) error {
+	hash, err := romHash(romPath)
+	if err != nil {
+		return err
+	}
+	for _, arg := range slots {
+		slot, err := parseSlot(arg)
+		if err != nil {
+			return err
+		}
+		if err := store.Remove(hash, slot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportState copies a slot into a file, or only its thumbnail if the file ends in .png
+func exportState(store savestate.Store, romPath string, slotArg string, path string) error {
+	hash, err := romHash(romPath)
+	if err != nil {
+		return err
+	}
+	slot, err := parseSlot(slotArg)
+	if err != nil {
+		return err
+	}
+	state, err := store.Load(hash, slot)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".png") {
+		return ioutil.WriteFile(path, state.Thumbnail, 0644)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := state.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// importState stores an exported state in the slots of its rom, by default
+// in the first free slot
+func importState(store savestate.Store, args []string) error {
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	state, err := savestate.Read(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %s", args[0], err)
+	}
+
+	slot := 1
+	if len(args) == 2 {
+		if slot, err = parseSlot(args[1]); err != nil {
+			return err
+		}
+	} else {
+		slots, err := store.List(state.RomHash)
+		if err != nil {
+			return err
+		}
+		for _, s := range slots {
+			if s.Number == slot {
+				slot++
+			}
+		}
+		if slot > input.StateSlots {
+			return fmt.Errorf("all %d slots of %s are in use", input.StateSlots, state.RomName)
+		}
+	}
+
+	if err := store.Save(state, slot); err != nil {
+		return err
+	}
+	fmt.Printf("imported %s into slot %d of %s\n", args[0], slot, state.RomName)
+	return nil
+}
+
+func romHash(path string) (string, error) {
+	rom, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return movie.RomHash(rom), nil
+}
+
+// parseSlot parses a slot number the hotkeys can reach
+func parseSlot(arg string) (int, error) {
+	slot, err := strconv.Atoi(arg)
+	if err != nil || slot < 1 || slot > input.StateSlots {
+		return 0, fmt.Errorf("invalid slot %q, expected 1 to %d", arg, input.StateSlots)
+	}
+	return slot, nil
+}
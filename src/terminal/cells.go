@@ -0,0 +1,164 @@
+package terminal
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Cell is one character of terminal output. Foreground and Background are
+// brightness levels between 0 and 0xFF that the display turns into colours.
+type Cell struct {
+	Rune       rune
+	Foreground uint8
+	Background uint8
+}
+
+// Encoder packs a screen into character cells of CellWidth by CellHeight pixels
+type Encoder struct {
+	Name       string
+	CellWidth  int
+	CellHeight int
+	encode     func(dst []Cell, levels []uint8, w, h, cols, rows int)
+}
+
+var (
+	// HalfBlocks draws two vertically stacked pixels per cell, which makes
+	// them roughly square. The upper pixel is the foreground colour of ▀ and
+	// the lower one the background, so every pixel keeps its own brightness.
+	HalfBlocks = Encoder{Name: "halfblocks", CellWidth: 1, CellHeight: 2, encode: halfBlocks}
+	// Quadrants draws 2x2 pixels per cell, halving the columns needed
+	Quadrants = Encoder{Name: "quadrants", CellWidth: 2, CellHeight: 2, encode: quadrants}
+	// Braille draws 2x4 pixels per cell as Braille dots, so even hires
+	// screens fit in a small terminal
+	Braille = Encoder{Name: "braille", CellWidth: 2, CellHeight: 4, encode: braille}
+)
+
+// Encoders are the available character encodings by name
+var Encoders = map[string]Encoder{
+	HalfBlocks.Name: HalfBlocks,
+	Quadrants.Name:  Quadrants,
+	Braille.Name:    Braille,
+}
+
+// EncoderNames returns the names of the available encoders in order
+func EncoderNames() []string {
+	names := make([]string, 0, len(Encoders))
+	for name := range Encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Auto is not an encoder itself, it stands for the result of Fit
+const Auto = "auto"
+
+// ParseEncoder returns the encoder called name. For Auto the zero Encoder
+// is returned.
+func ParseEncoder(name string) (Encoder, error) {
+	if name == Auto {
+		return Encoder{}, nil
+	}
+	e, ok := Encoders[name]
+	if !ok {
+		return Encoder{}, fmt.Errorf("unknown terminal encoding %q", name)
+	}
+	return e, nil
+}
+
+// Fit returns the encoder with the largest cells that still draws a w by h
+// screen in cols by rows cells, or Braille if none does
+func Fit(cols, rows, w, h int) Encoder {
+	for _, e := range []Encoder{HalfBlocks, Quadrants} {
+		if c, r := e.Size(w, h); c <= cols && r <= rows {
+			return e
+		}
+	}
+	return Braille
+}
+
+// Size returns the number of columns and rows needed for a w by h screen
+func (e Encoder) Size(w, h int) (int, int) {
+	return (w + e.CellWidth - 1) / e.CellWidth, (h + e.CellHeight - 1) / e.CellHeight
+}
+
+// Encode packs the brightness levels of a w by h screen into cells, row by row
+func (e Encoder) Encode(levels []uint8, w, h int) []Cell {
+	cols, rows := e.Size(w, h)
+	cells := make([]Cell, cols*rows)
+	e.encode(cells, levels, w, h, cols, rows)
+	return cells
+}
+
+// level returns the brightness at x, y or 0 outside of the screen
+func level(levels []uint8, w, h, x, y int) uint8 {
+	if x >= w || y >= h {
+		return 0
+	}
+	return levels[y*w+x]
+}
+
+func halfBlocks(dst []Cell, levels []uint8, w, h, cols, rows int) {
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			top, bottom := level(levels, w, h, col, row*2), level(levels, w, h, col, row*2+1)
+			if top == bottom {
+				dst[row*cols+col] = Cell{Rune: ' ', Background: bottom}
+			} else {
+				dst[row*cols+col] = Cell{Rune: '▀', Foreground: top, Background: bottom}
+			}
+		}
+	}
+}
+
+// quadrantRunes is indexed by a bit mask of the lit pixels: 1 is the top
+// left, 2 the top right, 4 the bottom left and 8 the bottom right
+var quadrantRunes = []rune(" ▘▝▀▖▌▞▛▗▚▐▜▄▙▟█")
+
+func quadrants(dst []Cell, levels []uint8, w, h, cols, rows int) {
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			var dots [4]uint8
+			for i := range dots {
+				dots[i] = level(levels, w, h, col*2+i%2, row*2+i/2)
+			}
+			mask, fg, bg := twoColours(dots[:])
+			dst[row*cols+col] = Cell{Rune: quadrantRunes[mask], Foreground: fg, Background: bg}
+		}
+	}
+}
+
+// brailleDots lists the pixels of a Braille cell in the order of their bits
+var brailleDots = [8]struct{ x, y int }{
+	{0, 0}, {0, 1}, {0, 2}, {1, 0}, {1, 1}, {1, 2}, {0, 3}, {1, 3},
+}
+
+func braille(dst []Cell, levels []uint8, w, h, cols, rows int) {
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			var dots [8]uint8
+			for i, d := range brailleDots {
+				dots[i] = level(levels, w, h, col*2+d.x, row*4+d.y)
+			}
+			mask, fg, bg := twoColours(dots[:])
+			dst[row*cols+col] = Cell{Rune: rune(0x2800 + mask), Foreground: fg, Background: bg}
+		}
+	}
+}
+
+// twoColours reduces the pixels of a cell to a bit mask of the lit ones and
+// a brightness for each kind. A cell only has two colours, so faded pixels
+// count as lit from half brightness and take the brightest level of their kind.
+func twoColours(dots []uint8) (mask int, fg uint8, bg uint8) {
+	for i, l := range dots {
+		if l >= 0x80 {
+			mask |= 1 << i
+			if l > fg {
+				fg = l
+			}
+		} else if l > bg {
+			bg = l
+		}
+	}
+	return mask, fg, bg
+}
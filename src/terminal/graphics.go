@@ -0,0 +1,159 @@
+package terminal
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+)
+
+const (
+	Kitty = "kitty"
+	Sixel = "sixel"
+	Text  = "text"
+)
+
+// Detect guesses the best way to draw in the terminal from its environment
+// variables: the kitty graphics protocol, sixel images or text
+func Detect(getenv func(string) string) string {
+	term, program := getenv("TERM"), getenv("TERM_PROGRAM")
+	switch {
+	case getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || term == "xterm-ghostty":
+		return Kitty
+	case program == "WezTerm" || program == "ghostty":
+		return Kitty
+	case strings.Contains(term, "sixel") || term == "foot" || term == "mlterm" || term == "contour":
+		return Sixel
+	case program == "iTerm.app" || program == "mintty":
+		return Sixel
+	}
+	return Text
+}
+
+// WriteSixel writes img as a sixel image with every pixel enlarged to a
+// scale by scale block. It can use at most 256 different colours.
+func WriteSixel(w io.Writer, img *image.RGBA, scale int) error {
+	if scale < 1 {
+		scale = 1
+	}
+	bounds := img.Bounds()
+	width, height := bounds.Dx()*scale, bounds.Dy()*scale
+
+	palette := map[color.RGBA]int{}
+	var colours []color.RGBA
+	indices := make([]int, bounds.Dx()*bounds.Dy())
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			c := img.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
+			i, ok := palette[c]
+			if !ok {
+				if len(colours) == 256 {
+					return fmt.Errorf("image has more than 256 colours")
+				}
+				i = len(colours)
+				palette[c] = i
+				colours = append(colours, c)
+			}
+			indices[y*bounds.Dx()+x] = i
+		}
+	}
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "\x1bP0;1;0q\"1;1;%d;%d", width, height)
+	for i, c := range colours {
+		fmt.Fprintf(b, "#%d;2;%d;%d;%d", i, int(c.R)*100/0xFF, int(c.G)*100/0xFF, int(c.B)*100/0xFF)
+	}
+
+	// Sixel data is sent in bands of 6 rows, one pass over the band per colour
+	for band := 0; band < height; band += 6 {
+		for i := range colours {
+			fmt.Fprintf(b, "#%d", i)
+			var run byte
+			count := 0
+			for x := 0; x < width; x++ {
+				bits := byte(0)
+				for row := 0; row < 6 && band+row < height; row++ {
+					if indices[(band+row)/scale*bounds.Dx()+x/scale] == i {
+						bits |= 1 << row
+					}
+				}
+				if count > 0 && bits != run {
+					writeSixelRun(b, run, count)
+					count = 0
+				}
+				run = bits
+				count++
+			}
+			writeSixelRun(b, run, count)
+			b.WriteByte('$')
+		}
+		b.WriteByte('-')
+	}
+	b.WriteString("\x1b\\")
+	return b.Flush()
+}
+
+func writeSixelRun(b *bufio.Writer, bits byte, count int) {
+	c := '?' + bits
+	if count > 3 {
+		fmt.Fprintf(b, "!%d%c", count, c)
+		return
+	}
+	for i := 0; i < count; i++ {
+		b.WriteByte(c)
+	}
+}
+
+// kittyChunk is the largest payload the kitty graphics protocol accepts per escape code
+const kittyChunk = 4096
+
+// WriteKitty writes img with the kitty graphics protocol, scaled by the
+// terminal to cover cols by rows cells. Every call replaces the image of the
+// previous one without moving the cursor.
+func WriteKitty(w io.Writer, img *image.RGBA, cols, rows int) error {
+	bounds := img.Bounds()
+	compressed := bytes.Buffer{}
+	z := zlib.NewWriter(&compressed)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		start := img.PixOffset(bounds.Min.X, y)
+		if _, err := z.Write(img.Pix[start : start+bounds.Dx()*4]); err != nil {
+			return err
+		}
+	}
+	if err := z.Close(); err != nil {
+		return err
+	}
+	payload := base64.StdEncoding.EncodeToString(compressed.Bytes())
+
+	b := bufio.NewWriter(w)
+	for first := true; first || len(payload) > 0; first = false {
+		chunk := payload
+		if len(chunk) > kittyChunk {
+			chunk = chunk[:kittyChunk]
+		}
+		payload = payload[len(chunk):]
+
+		more := 0
+		if len(payload) > 0 {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(b, "\x1b_Ga=T,f=32,o=z,s=%d,v=%d,c=%d,r=%d,i=1,p=1,q=2,C=1,m=%d;%s\x1b\\",
+				bounds.Dx(), bounds.Dy(), cols, rows, more, chunk)
+		} else {
+			fmt.Fprintf(b, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	return b.Flush()
+}
+
+// ClearKitty removes the images drawn by WriteKitty
+func ClearKitty(w io.Writer) error {
+	_, err := io.WriteString(w, "\x1b_Ga=d,q=2\x1b\\")
+	return err
+}
@@ -0,0 +1,76 @@
+package terminal
+
+import (
+	"chip8/src/input"
+	"fmt"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	DefaultFirstRelease = 500 * time.Millisecond
+	DefaultRelease      = 100 * time.Millisecond
+)
+
+// Keyboard emulates key releases for terminals, which only report presses.
+// A held key is repeated by the terminal, so a key counts as down until no
+// repeat has arrived for a while.
+type Keyboard struct {
+	// FirstRelease is how long a key stays down after its first press. It has
+	// to cover the delay before the terminal starts repeating the key.
+	FirstRelease time.Duration
+	// Release is how long a key stays down after a repeat
+	Release time.Duration
+
+	binds    map[rune]uint8
+	releases [16]time.Time
+	now      func() time.Time
+}
+
+// NewKeyboard binds the single character key names of keymap, ignoring case
+func NewKeyboard(keymap input.Keymap) (*Keyboard, error) {
+	binds := map[rune]uint8{}
+	for name, key := range keymap {
+		r, size := utf8.DecodeRuneInString(name)
+		if size == len(name) && r != utf8.RuneError {
+			binds[unicode.ToLower(r)] = key
+		}
+	}
+	if len(binds) == 0 {
+		return nil, fmt.Errorf("keymap has no keys a terminal can report")
+	}
+
+	return &Keyboard{
+		FirstRelease: DefaultFirstRelease,
+		Release:      DefaultRelease,
+		binds:        binds,
+		now:          time.Now,
+	}, nil
+}
+
+// Press handles a key press or repeat reported by the terminal, returning
+// false if r is not bound
+func (k *Keyboard) Press(r rune) bool {
+	key, ok := k.binds[unicode.ToLower(r)]
+	if !ok {
+		return false
+	}
+
+	now := k.now()
+	if k.IsDown(key) {
+		k.releases[key] = now.Add(k.Release)
+	} else {
+		k.releases[key] = now.Add(k.FirstRelease)
+	}
+	return true
+}
+
+// ReleaseAll releases every key at once
+func (k *Keyboard) ReleaseAll() {
+	k.releases = [16]time.Time{}
+}
+
+func (k *Keyboard) IsDown(key uint8) bool {
+	return k.now().Before(k.releases[key&0xF])
+}
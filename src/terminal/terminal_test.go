@@ -0,0 +1,225 @@
+package terminal
+
+import (
+	"bytes"
+	"chip8/src/input"
+	"compress/zlib"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestHalfBlocks(t *testing.T) {
+	levels := []uint8{
+		0xFF, 0x00, 0xFF,
+		0x00, 0x00, 0xFF,
+		0x80, 0x00, 0x00,
+	}
+	cells := HalfBlocks.Encode(levels, 3, 3)
+
+	expected := []Cell{
+		{'▀', 0xFF, 0x00}, {' ', 0x00, 0x00}, {' ', 0x00, 0xFF},
+		{'▀', 0x80, 0x00}, {' ', 0x00, 0x00}, {' ', 0x00, 0x00},
+	}
+	if len(cells) != len(expected) {
+		t.Fatalf("Expected %d cells, got %d", len(expected), len(cells))
+	}
+	for i := range cells {
+		if cells[i] != expected[i] {
+			t.Errorf("Cell %d: expected %q %v, got %q %v", i, expected[i].Rune, expected[i], cells[i].Rune, cells[i])
+		}
+	}
+}
+
+func TestQuadrants(t *testing.T) {
+	levels := []uint8{
+		0xFF, 0x00, 0xFF, 0xFF,
+		0x00, 0xC0, 0x00, 0x40,
+	}
+	cells := Quadrants.Encode(levels, 4, 2)
+
+	if cols, rows := Quadrants.Size(4, 2); cols != 2 || rows != 1 || len(cells) != 2 {
+		t.Fatalf("Expected 2x1 cells, got %dx%d", cols, rows)
+	}
+	if cells[0] != (Cell{'▚', 0xFF, 0x00}) {
+		t.Errorf("Expected a diagonal, got %q %v", cells[0].Rune, cells[0])
+	}
+	if cells[1] != (Cell{'▀', 0xFF, 0x40}) {
+		t.Errorf("Expected an upper half with a faded background, got %q %v", cells[1].Rune, cells[1])
+	}
+}
+
+func TestKeyboard(t *testing.T) {
+	k, err := NewKeyboard(input.Keymap{"Q": 0x4, "Keypad 5": 0x5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(0, 0)
+	k.now = func() time.Time { return now }
+
+	if k.Press('x') || k.IsDown(0x4) {
+		t.Errorf("Expected unbound keys to be ignored")
+	}
+
+	if !k.Press('q') || !k.IsDown(0x4) {
+		t.Errorf("Expected q to press key 4")
+	}
+
+	// The first press lasts until key repeat starts
+	now = now.Add(400 * time.Millisecond)
+	if !k.IsDown(0x4) {
+		t.Errorf("Expected key 4 to wait for key repeat")
+	}
+
+	// Repeats keep it down for a shorter time
+	k.Press('Q')
+	now = now.Add(90 * time.Millisecond)
+	if !k.IsDown(0x4) {
+		t.Errorf("Expected a repeat to hold key 4")
+	}
+	now = now.Add(20 * time.Millisecond)
+	if k.IsDown(0x4) {
+		t.Errorf("Expected key 4 to be released once repeats stop")
+	}
+
+	if _, err := NewKeyboard(input.Keymap{"Keypad 5": 0x5}); err == nil {
+		t.Errorf("Expected a keymap without characters to fail")
+	}
+}
+
+func TestBraille(t *testing.T) {
+	levels := []uint8{
+		0xFF, 0x00,
+		0x00, 0xFF,
+		0x00, 0x00,
+		0x40, 0xFF,
+	}
+	cells := Braille.Encode(levels, 2, 4)
+
+	if len(cells) != 1 || cells[0] != (Cell{'⢑', 0xFF, 0x40}) {
+		t.Errorf("Expected dots 1, 5 and 8, got %q %v", cells[0].Rune, cells)
+	}
+	if cols, rows := Braille.Size(128, 64); cols != 64 || rows != 16 {
+		t.Errorf("Expected hires to fit in 64x16 cells, got %dx%d", cols, rows)
+	}
+}
+
+func TestFit(t *testing.T) {
+	for _, c := range []struct {
+		cols, rows int
+		expected   Encoder
+	}{
+		{80, 24, HalfBlocks},
+		{64, 16, HalfBlocks},
+		{63, 16, Quadrants},
+		{40, 20, Quadrants},
+		{32, 8, Braille},
+		{10, 5, Braille},
+	} {
+		if e := Fit(c.cols, c.rows, 64, 32); e.Name != c.expected.Name {
+			t.Errorf("%dx%d: expected %s, got %s", c.cols, c.rows, c.expected.Name, e.Name)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, c := range []struct {
+		env      map[string]string
+		expected string
+	}{
+		{map[string]string{"TERM": "xterm-kitty"}, Kitty},
+		{map[string]string{"TERM": "xterm-256color", "KITTY_WINDOW_ID": "1"}, Kitty},
+		{map[string]string{"TERM_PROGRAM": "WezTerm"}, Kitty},
+		{map[string]string{"TERM": "foot"}, Sixel},
+		{map[string]string{"TERM": "xterm-sixel"}, Sixel},
+		{map[string]string{"TERM": "xterm-256color"}, Text},
+		{map[string]string{}, Text},
+	} {
+		if got := Detect(func(k string) string { return c.env[k] }); got != c.expected {
+			t.Errorf("%v: expected %s, got %s", c.env, c.expected, got)
+		}
+	}
+}
+
+func TestWriteSixel(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF})
+	img.SetRGBA(1, 0, color.RGBA{A: 0xFF})
+
+	out := bytes.Buffer{}
+	if err := WriteSixel(&out, img, 1); err != nil {
+		t.Fatal(err)
+	}
+	expected := "\x1bP0;1;0q\"1;1;2;1#0;2;100;100;100#1;2;0;0;0#0@?$#1?@$-\x1b\\"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+
+	// Scaled up, the first colour fills the 4 rows of the first 4 columns
+	out.Reset()
+	_ = WriteSixel(&out, img, 4)
+	if !bytes.Contains(out.Bytes(), []byte("#0!4N!4?$#1!4?!4N$-")) {
+		t.Errorf("Expected run length encoded sixels, got %q", out.String())
+	}
+}
+
+func TestWriteKitty(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 32))
+	img.SetRGBA(3, 2, color.RGBA{R: 0x12, G: 0x34, B: 0x56, A: 0xFF})
+
+	out := bytes.Buffer{}
+	if err := WriteKitty(&out, img, 40, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	codes := regexp.MustCompile("\x1b_G([^;]*);([^\x1b]*)\x1b\\\\").FindAllStringSubmatch(out.String(), -1)
+	if len(codes) != 1 {
+		t.Fatalf("Expected one escape code, got %q", out.String())
+	}
+	if codes[0][1] != "a=T,f=32,o=z,s=64,v=32,c=40,r=10,i=1,p=1,q=2,C=1,m=0" {
+		t.Errorf("Unexpected control data %q", codes[0][1])
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(codes[0][2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	z, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pixels, _ := ioutil.ReadAll(z)
+	if !bytes.Equal(pixels, img.Pix) {
+		t.Errorf("Expected the payload to be the image pixels")
+	}
+}
+
+func TestWriteKitty_Chunks(t *testing.T) {
+	// Noise does not compress, so the payload needs several chunks
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	seed := uint32(1)
+	for i := range img.Pix {
+		seed = seed*1664525 + 1013904223
+		img.Pix[i] = uint8(seed >> 24)
+	}
+
+	out := bytes.Buffer{}
+	if err := WriteKitty(&out, img, 8, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	codes := regexp.MustCompile("\x1b_G([^;]*);([^\x1b]*)\x1b\\\\").FindAllStringSubmatch(out.String(), -1)
+	if len(codes) < 4 {
+		t.Fatalf("Expected at least 4 chunks, got %d", len(codes))
+	}
+	for i, code := range codes {
+		last := i == len(codes)-1
+		if last != regexp.MustCompile("m=0$").MatchString(code[1]) || len(code[2]) > kittyChunk {
+			t.Errorf("Chunk %d: unexpected control data %q or length %d", i, code[1], len(code[2]))
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"chip8/src/chip8"
+	"chip8/src/tracing"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// traceDiff implements `chip8 tracediff a.trace b.trace`. It returns 0 when
+// the traces are identical, 1 when they diverge and 2 on errors.
+func traceDiff(args []string) int {
+	flags := flag.NewFlagSet("tracediff", flag.ExitOnError)
+	context := flags.Int("context", 5, "number of instructions to show around the divergence")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "usage: chip8 tracediff [-context n] a.trace b.trace")
+		return 2
+	}
+
+	a, err := readTrace(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	b, err := readTrace(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	d, diverged := tracing.Diff(a, b)
+	if !diverged {
+		fmt.Printf("Traces are identical (%d instructions)\n", len(a))
+		return 0
+	}
+
+	tracing.WriteReport(os.Stdout, a, b, d, *context)
+	return 1
+}
+
+func readTrace(path string) ([]chip8.TraceEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := tracing.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,110 @@
+package tracing
+
+import (
+	"chip8/src/chip8"
+	"fmt"
+	"io"
+)
+
+// Divergence is the first point where two traces disagree
+type Divergence struct {
+	// Index into both traces of the first differing entry
+	Index int
+	// Fields names what differs, for example "PC", "V3" or "writes"
+	Fields []string
+}
+
+// Diff lines up a and b instruction by instruction and returns the first
+// divergence. The boolean is false when the traces are identical.
+func Diff(a []chip8.TraceEntry, b []chip8.TraceEntry) (Divergence, bool) {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+
+	for i := 0; i < n; i++ {
+		if fields := compare(a[i], b[i]); len(fields) > 0 {
+			return Divergence{Index: i, Fields: fields}, true
+		}
+	}
+
+	if len(a) != len(b) {
+		return Divergence{Index: n, Fields: []string{"length"}}, true
+	}
+
+	return Divergence{}, false
+}
+
+func compare(a chip8.TraceEntry, b chip8.TraceEntry) []string {
+	var fields []string
+	if a.PC != b.PC {
+		fields = append(fields, "PC")
+	}
+	if a.Opcode != b.Opcode {
+		fields = append(fields, "opcode")
+	}
+	for i := range a.V {
+		if a.V[i] != b.V[i] {
+			fields = append(fields, fmt.Sprintf("V%X", i))
+		}
+	}
+	if a.I != b.I {
+		fields = append(fields, "I")
+	}
+	if a.SP != b.SP {
+		fields = append(fields, "SP")
+	}
+	if a.DT != b.DT {
+		fields = append(fields, "DT")
+	}
+	if a.ST != b.ST {
+		fields = append(fields, "ST")
+	}
+	if !sameWrites(a.Writes, b.Writes) {
+		fields = append(fields, "writes")
+	}
+
+	return fields
+}
+
+func sameWrites(a []chip8.MemoryWrite, b []chip8.MemoryWrite) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteReport prints d along with up to context entries of both traces on
+// either side of it.
+func WriteReport(w io.Writer, a []chip8.TraceEntry, b []chip8.TraceEntry, d Divergence, context int) {
+	fmt.Fprintf(w, "Traces diverge at instruction %d: %v\n", d.Index+1, d.Fields)
+
+	from := d.Index - context
+	if from < 0 {
+		from = 0
+	}
+	writeSide(w, "a", a, from, d.Index+context+1, d.Index)
+	writeSide(w, "b", b, from, d.Index+context+1, d.Index)
+}
+
+func writeSide(w io.Writer, name string, entries []chip8.TraceEntry, from int, to int, mark int) {
+	fmt.Fprintf(w, "\n--- %s\n", name)
+	if to > len(entries) {
+		to = len(entries)
+	}
+	for i := from; i < to; i++ {
+		prefix := "  "
+		if i == mark {
+			prefix = "> "
+		}
+		fmt.Fprintln(w, prefix+FormatEntry(entries[i]))
+	}
+	if mark >= len(entries) {
+		fmt.Fprintln(w, "> <end of trace>")
+	}
+}
@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"bytes"
+	"chip8/src/chip8"
+	"strings"
+	"testing"
+)
+
+func TestFormatEntry_RoundTrip(t *testing.T) {
+	e := chip8.TraceEntry{
+		Step:   42,
+		PC:     0x20A,
+		Opcode: 0xF255,
+		I:      0x300,
+		SP:     1,
+		DT:     0x10,
+		ST:     2,
+		Writes: []chip8.MemoryWrite{{Address: 0x300, Value: 1}, {Address: 0x301, Value: 0xFF}},
+	}
+	e.V[0xF] = 1
+
+	parsed, err := ParseEntry(FormatEntry(e))
+	if err != nil {
+		t.Fatalf("Unable to parse entry: %s", err)
+	}
+
+	if _, diverged := Diff([]chip8.TraceEntry{e}, []chip8.TraceEntry{parsed}); diverged || parsed.Step != e.Step {
+		t.Errorf("Expected %s, got %s", FormatEntry(e), FormatEntry(parsed))
+	}
+}
+
+func TestDiff_FirstDivergence(t *testing.T) {
+	a := recordTrace([]byte{0x60, 0x01, 0x61, 0x02, 0x80, 0x14, 0x12, 0x06})
+	b := recordTrace([]byte{0x60, 0x01, 0x61, 0x03, 0x80, 0x14, 0x12, 0x06})
+
+	d, diverged := Diff(a, b)
+	if !diverged {
+		t.Fatalf("Expected traces to diverge")
+	}
+
+	if d.Index != 1 {
+		t.Errorf("Expected divergence at index 1, got %d", d.Index)
+	}
+
+	if len(d.Fields) != 2 || d.Fields[0] != "opcode" || d.Fields[1] != "V1" {
+		t.Errorf("Expected opcode and V1 to differ, got %v", d.Fields)
+	}
+
+	out := bytes.Buffer{}
+	WriteReport(&out, a, b, d, 1)
+	if !strings.Contains(out.String(), "> 000000000002 0202 6103") {
+		t.Errorf("Expected report to mark the diverging instruction, got:\n%s", out.String())
+	}
+}
+
+func TestDiff_Length(t *testing.T) {
+	a := recordTrace([]byte{0x60, 0x01, 0x61, 0x02, 0x80, 0x14, 0x12, 0x06})
+
+	d, diverged := Diff(a, a[:2])
+	if !diverged || d.Index != 2 || d.Fields[0] != "length" {
+		t.Errorf("Expected a length divergence at index 2, got %v", d)
+	}
+
+	if _, diverged := Diff(a, a); diverged {
+		t.Errorf("Expected identical traces not to diverge")
+	}
+}
+
+func recordTrace(code []byte) []chip8.TraceEntry {
+	cpu := chip8.NewCPU(0x300, nil, nil)
+	_ = cpu.LoadProgram(bytes.NewReader(code))
+
+	out := bytes.Buffer{}
+	cpu.SetTracer(&Writer{To: &out})
+	for i := 0; i < 6; i++ {
+		cpu.Step()
+	}
+
+	entries, err := Read(&out)
+	if err != nil {
+		panic(err)
+	}
+	return entries
+}
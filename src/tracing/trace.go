@@ -0,0 +1,137 @@
+package tracing
+
+import (
+	"bufio"
+	"chip8/src/chip8"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Writer records every instruction executed by a cpu as one line of text.
+// Each line looks like:
+//
+//	000000000001 0200 6a02 V=0a000000000000000000000000000000 I=0000 SP=00 DT=00 ST=00 W=-
+type Writer struct {
+	To  io.Writer
+	Err error
+}
+
+func (w *Writer) Trace(e chip8.TraceEntry) {
+	if w.Err != nil {
+		return
+	}
+	_, w.Err = fmt.Fprintln(w.To, FormatEntry(e))
+}
+
+// FormatEntry returns the textual trace representation of e
+func FormatEntry(e chip8.TraceEntry) string {
+	writes := "-"
+	if len(e.Writes) > 0 {
+		parts := make([]string, len(e.Writes))
+		for i, w := range e.Writes {
+			parts[i] = fmt.Sprintf("%04x:%02x", w.Address, w.Value)
+		}
+		writes = strings.Join(parts, ",")
+	}
+
+	return fmt.Sprintf(
+		"%012d %04x %04x V=%s I=%04x SP=%02x DT=%02x ST=%02x W=%s",
+		e.Step, e.PC, e.Opcode, hex.EncodeToString(e.V[:]), e.I, e.SP, e.DT, e.ST, writes,
+	)
+}
+
+// ParseEntry reads a single line produced by FormatEntry
+func ParseEntry(line string) (chip8.TraceEntry, error) {
+	var e chip8.TraceEntry
+	fields := strings.Fields(line)
+	if len(fields) != 9 {
+		return e, fmt.Errorf("expected 9 fields, got %d", len(fields))
+	}
+
+	step, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return e, fmt.Errorf("invalid step: %s", err)
+	}
+	e.Step = step
+
+	pc, err := strconv.ParseUint(fields[1], 16, 16)
+	if err != nil {
+		return e, fmt.Errorf("invalid pc: %s", err)
+	}
+	e.PC = uint16(pc)
+
+	opcode, err := strconv.ParseUint(fields[2], 16, 16)
+	if err != nil {
+		return e, fmt.Errorf("invalid opcode: %s", err)
+	}
+	e.Opcode = uint16(opcode)
+
+	values := map[string]string{}
+	for _, f := range fields[3:] {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			return e, fmt.Errorf("invalid field %q", f)
+		}
+		values[kv[0]] = kv[1]
+	}
+
+	v, err := hex.DecodeString(values["V"])
+	if err != nil || len(v) != len(e.V) {
+		return e, fmt.Errorf("invalid registers %q", values["V"])
+	}
+	copy(e.V[:], v)
+
+	for _, r := range []struct {
+		name string
+		bits int
+		set  func(uint64)
+	}{
+		{"I", 16, func(n uint64) { e.I = uint16(n) }},
+		{"SP", 16, func(n uint64) { e.SP = uint16(n) }},
+		{"DT", 8, func(n uint64) { e.DT = uint8(n) }},
+		{"ST", 8, func(n uint64) { e.ST = uint8(n) }},
+	} {
+		n, err := strconv.ParseUint(values[r.name], 16, r.bits)
+		if err != nil {
+			return e, fmt.Errorf("invalid %s: %s", r.name, err)
+		}
+		r.set(n)
+	}
+
+	if values["W"] != "-" {
+		for _, w := range strings.Split(values["W"], ",") {
+			var addr uint16
+			var value uint8
+			if _, err := fmt.Sscanf(w, "%04x:%02x", &addr, &value); err != nil {
+				return e, fmt.Errorf("invalid memory write %q: %s", w, err)
+			}
+			e.Writes = append(e.Writes, chip8.MemoryWrite{Address: addr, Value: value})
+		}
+	}
+
+	return e, nil
+}
+
+// Read parses a whole trace. Empty lines are skipped.
+func Read(r io.Reader) ([]chip8.TraceEntry, error) {
+	var entries []chip8.TraceEntry
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		e, err := ParseEntry(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, scanner.Err()
+}
@@ -0,0 +1,78 @@
+package video
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Palette holds the colours of lit and unlit pixels
+type Palette struct {
+	Foreground color.RGBA
+	Background color.RGBA
+}
+
+const DefaultPalette = "mono"
+
+// Palettes are the built in colour schemes
+var Palettes = map[string]Palette{
+	"mono":  {Foreground: rgb(0xFFFFFF), Background: rgb(0x000000)},
+	"amber": {Foreground: rgb(0xFFB000), Background: rgb(0x1A0F00)},
+	"green": {Foreground: rgb(0x33FF66), Background: rgb(0x001A08)},
+	"lcd":   {Foreground: rgb(0x0F380F), Background: rgb(0x9BBC0F)},
+	"paper": {Foreground: rgb(0x202020), Background: rgb(0xF0EAD6)},
+}
+
+// PaletteNames returns the names of the built in palettes in order
+func PaletteNames() []string {
+	names := make([]string, 0, len(Palettes))
+	for name := range Palettes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParsePalette returns the built in palette called spec, or parses spec as
+// two hex colours for the foreground and background, e.g. ffb000,1a0f00
+func ParsePalette(spec string) (Palette, error) {
+	if p, ok := Palettes[spec]; ok {
+		return p, nil
+	}
+
+	parts := strings.Split(spec, ",")
+	if len(parts) != 2 {
+		return Palette{}, fmt.Errorf("unknown palette %q", spec)
+	}
+
+	var colours [2]color.RGBA
+	for i, part := range parts {
+		part = strings.TrimPrefix(strings.TrimSpace(part), "#")
+		v, err := strconv.ParseUint(part, 16, 24)
+		if err != nil || len(part) != 6 {
+			return Palette{}, fmt.Errorf("invalid colour %q", part)
+		}
+		colours[i] = rgb(uint32(v))
+	}
+	return Palette{Foreground: colours[0], Background: colours[1]}, nil
+}
+
+// Blend returns the colour of a pixel lit at level, where 0 is the background
+// and 0xFF the foreground
+func (p Palette) Blend(level uint8) color.RGBA {
+	mix := func(bg, fg uint8) uint8 {
+		return uint8((int(bg)*(0xFF-int(level)) + int(fg)*int(level)) / 0xFF)
+	}
+	return color.RGBA{
+		R: mix(p.Background.R, p.Foreground.R),
+		G: mix(p.Background.G, p.Foreground.G),
+		B: mix(p.Background.B, p.Foreground.B),
+		A: 0xFF,
+	}
+}
+
+func rgb(v uint32) color.RGBA {
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xFF}
+}
@@ -0,0 +1,91 @@
+package video
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Persistence imitates the afterglow of a CRT phosphor, which hides the
+// flicker of sprites that are erased and redrawn every frame. It is fed one
+// screen per frame and turns it into brightness levels.
+type Persistence struct {
+	// Blend averages every frame with the one before it instead of fading
+	Blend bool
+	// Frames is how many frames a pixel takes to fade out after it is turned off
+	Frames int
+
+	levels   []uint8
+	previous []bool
+	left     []int
+}
+
+// ParsePersistence parses off, blend or fade:N where N is the number of
+// frames pixels take to fade out. Off returns nil.
+func ParsePersistence(spec string) (*Persistence, error) {
+	switch {
+	case spec == "" || spec == "off":
+		return nil, nil
+	case spec == "blend":
+		return &Persistence{Blend: true}, nil
+	case strings.HasPrefix(spec, "fade:"):
+		frames, err := strconv.Atoi(strings.TrimPrefix(spec, "fade:"))
+		if err != nil || frames < 1 {
+			return nil, fmt.Errorf("invalid fade length in %q", spec)
+		}
+		return &Persistence{Frames: frames}, nil
+	}
+	return nil, fmt.Errorf("unknown persistence %q, expected off, blend or fade:N", spec)
+}
+
+// Update advances the effect by a frame and returns the brightness of every pixel
+func (p *Persistence) Update(pixels []bool) []uint8 {
+	if len(p.levels) != len(pixels) {
+		p.levels = make([]uint8, len(pixels))
+		p.previous = make([]bool, len(pixels))
+		p.left = make([]int, len(pixels))
+	}
+
+	if p.Blend {
+		for i, on := range pixels {
+			p.levels[i] = 0
+			if on {
+				p.levels[i] += 0x80
+			}
+			if p.previous[i] {
+				p.levels[i] += 0x7F
+			}
+		}
+		copy(p.previous, pixels)
+		return p.levels
+	}
+
+	// Every pixel counts the frames it has left, rounding its level up keeps
+	// it visible until the last of them even when Frames is above 0xFF
+	for i, on := range pixels {
+		switch {
+		case on:
+			p.left[i] = p.Frames
+		case p.left[i] > 0:
+			p.left[i]--
+		}
+		p.levels[i] = uint8((0xFF*p.left[i] + p.Frames - 1) / p.Frames)
+	}
+	return p.levels
+}
+
+// Levels returns the brightness levels of the last update
+func (p *Persistence) Levels() []uint8 {
+	return p.levels
+}
+
+// Settled reports whether another update with the same screen would change
+// nothing, which is the case once no pixel is part way faded
+func (p *Persistence) Settled() bool {
+	for _, level := range p.levels {
+		if level != 0 && level != 0xFF {
+			return false
+		}
+	}
+	return true
+}
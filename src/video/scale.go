@@ -0,0 +1,147 @@
+package video
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Filter is a pixel art upscaler that multiplies the image size by Factor
+type Filter struct {
+	Name   string
+	Factor int
+	apply  func(src []uint8, w, h int) []uint8
+}
+
+var (
+	NoFilter = Filter{Name: "none", Factor: 1}
+	Scale2x  = Filter{Name: "scale2x", Factor: 2, apply: scale2x}
+	Scale3x  = Filter{Name: "scale3x", Factor: 3, apply: scale3x}
+)
+
+// Filters are the available upscalers by name. EPX produces the same
+// result as Scale2x.
+var Filters = map[string]Filter{
+	"none":    NoFilter,
+	"scale2x": Scale2x,
+	"epx":     Scale2x,
+	"scale3x": Scale3x,
+}
+
+// FilterNames returns the names of the available filters in order
+func FilterNames() []string {
+	names := make([]string, 0, len(Filters))
+	for name := range Filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseFilter returns the filter called name
+func ParseFilter(name string) (Filter, error) {
+	f, ok := Filters[name]
+	if !ok {
+		return Filter{}, fmt.Errorf("unknown filter %q", name)
+	}
+	return f, nil
+}
+
+// Apply upscales the w by h image src
+func (f Filter) Apply(src []uint8, w, h int) []uint8 {
+	if f.apply == nil {
+		return src
+	}
+	return f.apply(src, w, h)
+}
+
+// neighbours reads pixels of a w by h image, repeating the edge pixels outside of it
+type neighbours struct {
+	src  []uint8
+	w, h int
+}
+
+func (n neighbours) at(x, y int) uint8 {
+	if x < 0 {
+		x = 0
+	} else if x >= n.w {
+		x = n.w - 1
+	}
+	if y < 0 {
+		y = 0
+	} else if y >= n.h {
+		y = n.h - 1
+	}
+	return n.src[y*n.w+x]
+}
+
+func scale2x(src []uint8, w, h int) []uint8 {
+	n := neighbours{src, w, h}
+	dst := make([]uint8, len(src)*4)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			a, b, c, d := n.at(x, y-1), n.at(x+1, y), n.at(x-1, y), n.at(x, y+1)
+			p := n.at(x, y)
+			e0, e1, e2, e3 := p, p, p, p
+			if c == a && c != d && a != b {
+				e0 = a
+			}
+			if a == b && a != c && b != d {
+				e1 = b
+			}
+			if d == c && d != b && c != a {
+				e2 = c
+			}
+			if b == d && b != a && d != c {
+				e3 = d
+			}
+
+			i := y*2*w*2 + x*2
+			dst[i], dst[i+1] = e0, e1
+			dst[i+w*2], dst[i+w*2+1] = e2, e3
+		}
+	}
+	return dst
+}
+
+func scale3x(src []uint8, w, h int) []uint8 {
+	n := neighbours{src, w, h}
+	dst := make([]uint8, len(src)*9)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			a, b, c := n.at(x-1, y-1), n.at(x, y-1), n.at(x+1, y-1)
+			d, e, f := n.at(x-1, y), n.at(x, y), n.at(x+1, y)
+			g, h2, i := n.at(x-1, y+1), n.at(x, y+1), n.at(x+1, y+1)
+
+			out := [9]uint8{e, e, e, e, e, e, e, e, e}
+			if d == b && b != f && d != h2 {
+				out[0] = d
+			}
+			if (d == b && b != f && d != h2 && e != c) || (b == f && b != d && f != h2 && e != a) {
+				out[1] = b
+			}
+			if b == f && b != d && f != h2 {
+				out[2] = f
+			}
+			if (d == b && b != f && d != h2 && e != g) || (d == h2 && d != b && h2 != f && e != a) {
+				out[3] = d
+			}
+			if (b == f && b != d && f != h2 && e != i) || (h2 == f && d != h2 && b != f && e != c) {
+				out[5] = f
+			}
+			if d == h2 && d != b && h2 != f {
+				out[6] = d
+			}
+			if (d == h2 && d != b && h2 != f && e != i) || (h2 == f && d != h2 && b != f && e != g) {
+				out[7] = h2
+			}
+			if h2 == f && d != h2 && b != f {
+				out[8] = f
+			}
+
+			for row := 0; row < 3; row++ {
+				copy(dst[(y*3+row)*w*3+x*3:], out[row*3:row*3+3])
+			}
+		}
+	}
+	return dst
+}
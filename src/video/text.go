@@ -0,0 +1,100 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"strings"
+)
+
+// glyphs is a 3 by 5 pixel font, every row is 3 bits with the left pixel in bit 2
+var glyphs = map[rune][5]uint8{
+	' ': {0, 0, 0, 0, 0},
+	'0': {7, 5, 5, 5, 7}, '1': {2, 6, 2, 2, 7}, '2': {7, 1, 7, 4, 7}, '3': {7, 1, 3, 1, 7},
+	'4': {5, 5, 7, 1, 1}, '5': {7, 4, 7, 1, 7}, '6': {7, 4, 7, 5, 7}, '7': {7, 1, 1, 2, 2},
+	'8': {7, 5, 7, 5, 7}, '9': {7, 5, 7, 1, 7},
+	'A': {2, 5, 7, 5, 5}, 'B': {6, 5, 6, 5, 6}, 'C': {3, 4, 4, 4, 3}, 'D': {6, 5, 5, 5, 6},
+	'E': {7, 4, 6, 4, 7}, 'F': {7, 4, 6, 4, 4}, 'G': {3, 4, 5, 5, 3}, 'H': {5, 5, 7, 5, 5},
+	'I': {7, 2, 2, 2, 7}, 'J': {1, 1, 1, 5, 2}, 'K': {5, 5, 6, 5, 5}, 'L': {4, 4, 4, 4, 7},
+	'M': {5, 7, 7, 5, 5}, 'N': {6, 5, 5, 5, 5}, 'O': {2, 5, 5, 5, 2}, 'P': {6, 5, 6, 4, 4},
+	'Q': {2, 5, 5, 6, 3}, 'R': {6, 5, 6, 5, 5}, 'S': {3, 4, 2, 1, 6}, 'T': {7, 2, 2, 2, 2},
+	'U': {5, 5, 5, 5, 7}, 'V': {5, 5, 5, 5, 2}, 'W': {5, 5, 7, 7, 5}, 'X': {5, 5, 2, 5, 5},
+	'Y': {5, 5, 2, 2, 2}, 'Z': {7, 1, 2, 4, 7},
+	'.': {0, 0, 0, 0, 2}, ',': {0, 0, 0, 2, 4}, ':': {0, 2, 0, 2, 0}, '!': {2, 2, 2, 0, 2},
+	'?': {6, 1, 2, 0, 2}, '-': {0, 0, 7, 0, 0}, '+': {0, 2, 7, 2, 0}, '=': {0, 7, 0, 7, 0},
+	'/': {1, 1, 2, 4, 4}, '%': {5, 1, 2, 4, 5}, '(': {1, 2, 2, 2, 1}, ')': {4, 2, 2, 2, 4},
+}
+
+// DrawNotice writes text in upper case into a bar along the bottom of an
+// image returned by Render. Text that does not fit is cut off.
+func (r *Renderer) DrawNotice(img *image.RGBA, text string) {
+	scale := r.fontScale()
+	bounds := img.Bounds()
+	top := bounds.Max.Y - 7*scale
+
+	fill(img, image.Rect(bounds.Min.X, top, bounds.Max.X, bounds.Max.Y), r.Palette.Background)
+	r.drawText(img, bounds.Min.X+scale, top+scale, text)
+}
+
+// DrawOverlay writes lines of text in upper case into a box in the top left
+// corner of an image returned by Render
+func (r *Renderer) DrawOverlay(img *image.RGBA, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	scale := r.fontScale()
+	bounds := img.Bounds()
+
+	longest := 0
+	for _, line := range lines {
+		if n := len([]rune(line)); n > longest {
+			longest = n
+		}
+	}
+	box := image.Rect(0, 0, (longest*4+1)*scale, (len(lines)*6+1)*scale).Add(bounds.Min)
+	fill(img, box, r.Palette.Background)
+	for i, line := range lines {
+		r.drawText(img, bounds.Min.X+scale, bounds.Min.Y+(i*6+1)*scale, line)
+	}
+}
+
+// fontScale makes font pixels as large as screen pixels
+func (r *Renderer) fontScale() int {
+	if r.Filter.Factor < 1 {
+		return 1
+	}
+	return r.Filter.Factor
+}
+
+// drawText writes text in upper case with its top left corner at x, y,
+// cutting off what does not fit
+func (r *Renderer) drawText(img *image.RGBA, x int, top int, text string) {
+	scale := r.fontScale()
+	bounds := img.Bounds()
+	for _, c := range strings.ToUpper(text) {
+		if x+3*scale > bounds.Max.X {
+			return
+		}
+		glyph, ok := glyphs[c]
+		if !ok {
+			glyph = glyphs['?']
+		}
+		for row, bits := range glyph {
+			for col := 0; col < 3; col++ {
+				if bits&(4>>col) != 0 {
+					y := top + row*scale
+					fill(img, image.Rect(x+col*scale, y, x+(col+1)*scale, y+scale), r.Palette.Foreground)
+				}
+			}
+		}
+		x += 4 * scale
+	}
+}
+
+func fill(img *image.RGBA, rect image.Rectangle, c color.RGBA) {
+	rect = rect.Intersect(img.Bounds())
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
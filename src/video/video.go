@@ -0,0 +1,79 @@
+package video
+
+import "image"
+
+// Renderer converts 1 bit screens into RGBA images, reusing the image between frames
+type Renderer struct {
+	Palette Palette
+	Filter  Filter
+	// Persistence is optional and advanced by every call to Render
+	Persistence *Persistence
+
+	levels []uint8
+	img    *image.RGBA
+}
+
+func NewRenderer(palette Palette, filter Filter) *Renderer {
+	return &Renderer{Palette: palette, Filter: filter}
+}
+
+// Size returns the size of the images rendered for a w by h screen
+func (r *Renderer) Size(w, h int) (int, int) {
+	factor := r.Filter.Factor
+	if factor < 1 {
+		factor = 1
+	}
+	return w * factor, h * factor
+}
+
+// Animating reports whether the image keeps changing while the screen stays the same
+func (r *Renderer) Animating() bool {
+	return r.Persistence != nil && !r.Persistence.Settled()
+}
+
+// Render draws the w by h screen pixels. It is meant to be called once per
+// frame, and the returned image is only valid until the next call.
+func (r *Renderer) Render(pixels []bool, w, h int) *image.RGBA {
+	if r.Persistence != nil {
+		return r.RenderLevels(r.Persistence.Update(pixels[:w*h]), w, h)
+	}
+
+	if len(r.levels) != w*h {
+		r.levels = make([]uint8, w*h)
+	}
+	levelsOf(r.levels, pixels[:w*h])
+	return r.RenderLevels(r.levels, w, h)
+}
+
+// Levels returns the brightness of pixels without any effects, 0xFF for lit pixels
+func Levels(pixels []bool) []uint8 {
+	levels := make([]uint8, len(pixels))
+	levelsOf(levels, pixels)
+	return levels
+}
+
+func levelsOf(dst []uint8, pixels []bool) {
+	for i, on := range pixels {
+		dst[i] = 0
+		if on {
+			dst[i] = 0xFF
+		}
+	}
+}
+
+// RenderLevels draws a w by h screen where every pixel has a brightness
+// between 0 and 0xFF
+func (r *Renderer) RenderLevels(levels []uint8, w, h int) *image.RGBA {
+	scaled := r.Filter.Apply(levels, w, h)
+	sw, sh := r.Size(w, h)
+	if r.img == nil || r.img.Rect.Dx() != sw || r.img.Rect.Dy() != sh {
+		r.img = image.NewRGBA(image.Rect(0, 0, sw, sh))
+	}
+
+	for i, level := range scaled {
+		c := r.Palette.Blend(level)
+		p := r.img.Pix[i*4 : i*4+4]
+		p[0], p[1], p[2], p[3] = c.R, c.G, c.B, c.A
+	}
+	return r.img
+}
@@ -0,0 +1,271 @@
+package video
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParsePalette(t *testing.T) {
+	p, err := ParsePalette("amber")
+	if err != nil || p != Palettes["amber"] {
+		t.Errorf("Expected the amber preset, got %v %v", p, err)
+	}
+
+	p, err = ParsePalette("#ff8000,102030")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Foreground != (color.RGBA{R: 0xFF, G: 0x80, A: 0xFF}) || p.Background != (color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xFF}) {
+		t.Errorf("Unexpected palette %v", p)
+	}
+
+	for _, spec := range []string{"neon", "ff8000", "ff8000,12345", "ff8000,zzzzzz"} {
+		if _, err := ParsePalette(spec); err == nil {
+			t.Errorf("Expected %q to fail", spec)
+		}
+	}
+}
+
+func TestPalette_Blend(t *testing.T) {
+	p := Palette{Foreground: rgb(0xFFFFFF), Background: rgb(0x000000)}
+	if p.Blend(0) != p.Background || p.Blend(0xFF) != p.Foreground {
+		t.Errorf("Expected the end points to be the palette colours")
+	}
+	if c := p.Blend(0x80); c.R != 0x80 {
+		t.Errorf("Expected a half way blend, got %v", c)
+	}
+}
+
+// diagonal is a 4x4 image with a two pixel diagonal line in the middle
+var diagonal = []uint8{
+	0, 0, 0, 0,
+	0, 1, 0, 0,
+	0, 0, 1, 0,
+	0, 0, 0, 0,
+}
+
+func TestScale2x(t *testing.T) {
+	got := Scale2x.Apply(diagonal, 4, 4)
+	expected := []uint8{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 1, 1, 0, 0, 0, 0,
+		0, 0, 1, 1, 1, 0, 0, 0,
+		0, 0, 0, 1, 1, 1, 0, 0,
+		0, 0, 0, 0, 1, 1, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	assertImage(t, got, expected, 8)
+}
+
+func TestScale3x(t *testing.T) {
+	got := Scale3x.Apply(diagonal, 4, 4)
+	expected := []uint8{
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 1, 1, 1, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 1, 1, 1, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 1, 1, 1, 1, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 1, 1, 1, 1, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 1, 1, 1, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 1, 1, 1, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	assertImage(t, got, expected, 12)
+}
+
+func TestNoFilter(t *testing.T) {
+	assertImage(t, NoFilter.Apply(diagonal, 4, 4), diagonal, 4)
+}
+
+func TestRenderer_Render(t *testing.T) {
+	r := NewRenderer(Palettes["lcd"], Scale2x)
+	img := r.Render([]bool{true, false}, 2, 1)
+
+	if img.Rect.Dx() != 4 || img.Rect.Dy() != 2 {
+		t.Fatalf("Expected a 4x2 image, got %v", img.Rect)
+	}
+	if img.RGBAAt(1, 1) != r.Palette.Foreground || img.RGBAAt(2, 0) != r.Palette.Background {
+		t.Errorf("Expected pixels in the palette colours")
+	}
+}
+
+func assertImage(t *testing.T, got, expected []uint8, w int) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d pixels, got %d", len(expected), len(got))
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("Pixel %d,%d: expected %d, got %d", i%w, i/w, expected[i], got[i])
+		}
+	}
+}
+
+func TestParsePersistence(t *testing.T) {
+	for _, c := range []struct {
+		spec     string
+		expected *Persistence
+	}{
+		{"off", nil},
+		{"", nil},
+		{"blend", &Persistence{Blend: true}},
+		{"fade:4", &Persistence{Frames: 4}},
+	} {
+		p, err := ParsePersistence(c.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (p == nil) != (c.expected == nil) || p != nil && (p.Blend != c.expected.Blend || p.Frames != c.expected.Frames) {
+			t.Errorf("%q: expected %v, got %v", c.spec, c.expected, p)
+		}
+	}
+
+	for _, spec := range []string{"fade", "fade:0", "fade:x", "glow"} {
+		if _, err := ParsePersistence(spec); err == nil {
+			t.Errorf("Expected %q to fail", spec)
+		}
+	}
+}
+
+func TestPersistence_Fade(t *testing.T) {
+	p := &Persistence{Frames: 3}
+	on, off := []bool{true}, []bool{false}
+
+	if l := p.Update(on); l[0] != 0xFF || !p.Settled() {
+		t.Errorf("Expected a lit pixel at full brightness, got %x", l[0])
+	}
+	if l := p.Update(off); l[0] != 0xAA || p.Settled() {
+		t.Errorf("Expected the pixel to fade to 0xAA, got %x", l[0])
+	}
+	if l := p.Update(off); l[0] != 0x55 {
+		t.Errorf("Expected the pixel to fade to 0x55, got %x", l[0])
+	}
+	if l := p.Update(off); l[0] != 0 || !p.Settled() {
+		t.Errorf("Expected the pixel to be off after 3 frames, got %x", l[0])
+	}
+	if l := p.Update(on); l[0] != 0xFF {
+		t.Errorf("Expected a redrawn pixel at full brightness, got %x", l[0])
+	}
+}
+
+func TestPersistence_FadeFrames(t *testing.T) {
+	for frames := 1; frames <= 0x200; frames++ {
+		p := &Persistence{Frames: frames}
+		p.Update([]bool{true})
+
+		count := 0
+		for l := p.Update([]bool{false}); count < 0x200; l = p.Update([]bool{false}) {
+			count++
+			if l[0] == 0 {
+				break
+			}
+		}
+		if count != frames {
+			t.Errorf("fade:%d: expected the pixel to be off after %d frames, got %d", frames, frames, count)
+		}
+	}
+}
+
+func TestPersistence_Blend(t *testing.T) {
+	p := &Persistence{Blend: true}
+
+	// A sprite that is redrawn every other frame stays visible at half brightness
+	for i, c := range []struct {
+		on       bool
+		expected uint8
+	}{
+		{true, 0x80},
+		{false, 0x7F},
+		{true, 0x80},
+		{true, 0xFF},
+		{false, 0x7F},
+		{false, 0},
+	} {
+		if l := p.Update([]bool{c.on}); l[0] != c.expected {
+			t.Errorf("Frame %d: expected %x, got %x", i, c.expected, l[0])
+		}
+	}
+}
+
+func TestRenderer_Persistence(t *testing.T) {
+	r := NewRenderer(Palettes["mono"], NoFilter)
+	r.Persistence = &Persistence{Frames: 2}
+
+	r.Render([]bool{true}, 1, 1)
+	if r.Animating() {
+		t.Errorf("Expected a lit pixel not to animate")
+	}
+	img := r.Render([]bool{false}, 1, 1)
+	if !r.Animating() || img.RGBAAt(0, 0).R != 0x80 {
+		t.Errorf("Expected a fading pixel, got %v", img.RGBAAt(0, 0))
+	}
+}
+
+func TestRenderer_DrawNotice(t *testing.T) {
+	r := NewRenderer(Palettes["mono"], NoFilter)
+	pixels := make([]bool, 12*8)
+	for i := range pixels {
+		pixels[i] = true
+	}
+	img := r.Render(pixels, 12, 8)
+	r.DrawNotice(img, "t?")
+
+	// A blank bar 7 pixels high with a T from x=1 and a ? from x=5 in it
+	expected := []string{
+		"############",
+		"............",
+		".###.##.....",
+		"..#....#....",
+		"..#...#.....",
+		"..#.........",
+		"..#...#.....",
+		"............",
+	}
+	for y, row := range expected {
+		for x, c := range row {
+			if lit := img.RGBAAt(x, y) == r.Palette.Foreground; lit != (c == '#') {
+				t.Errorf("Pixel %d,%d: expected %c", x, y, c)
+			}
+		}
+	}
+}
+
+func TestRenderer_DrawOverlay(t *testing.T) {
+	r := NewRenderer(Palettes["mono"], NoFilter)
+	pixels := make([]bool, 12*14)
+	for i := range pixels {
+		pixels[i] = true
+	}
+	img := r.Render(pixels, 12, 14)
+	r.DrawOverlay(img, []string{"1", "-="})
+
+	// A blank box around the longest line, the rest stays lit
+	expected := []string{
+		".........###",
+		"..#......###",
+		".##......###",
+		"..#......###",
+		"..#......###",
+		".###.....###",
+		".........###",
+		".........###",
+		".....###.###",
+		".###.....###",
+		".....###.###",
+		".........###",
+		".........###",
+		"############",
+	}
+	for y, row := range expected {
+		for x, c := range row {
+			if lit := img.RGBAAt(x, y) == r.Palette.Foreground; lit != (c == '#') {
+				t.Errorf("Pixel %d,%d: expected %c", x, y, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"chip8/src/chip8"
+	"chip8/src/displays"
+	"chip8/src/input"
+	"chip8/src/machine"
+	"chip8/src/terminal"
+	"chip8/src/video"
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// window is an interactive backend that draws the screen, reads the keypad
+// and handles its own events
+type window interface {
+	machine.Frontend
+	// Notify shows a short message for a few seconds
+	Notify(text string)
+	// SetStatus shows lines of text until it is called with none
+	SetStatus(lines []string)
+	Dispose()
+}
+
+type windowOptions struct {
+	backend string
+	screen  *video.Renderer
+	scaling string
+	cells   string
+	scale   int
+	keymap  string
+	padmap  string
+	romPath string
+	hotkeys input.Hotkeys
+	// onHotkey receives every press and release of a hotkey
+	onHotkey func(a input.Action, down bool)
+}
+
+// backends are the values of the -display flag besides auto
+var backends = []string{"sdl", "terminal", terminal.Sixel, terminal.Kitty}
+
+// chooseBackend picks the SDL window in graphical sessions. Otherwise, for
+// example over SSH, it picks the best drawing method the terminal supports.
+func chooseBackend(getenv func(string) string, goos string) string {
+	remote := getenv("SSH_CONNECTION") != "" || getenv("SSH_TTY") != ""
+	graphical := goos == "windows" || goos == "darwin" || getenv("DISPLAY") != "" || getenv("WAYLAND_DISPLAY") != ""
+	if graphical && !remote {
+		return "sdl"
+	}
+
+	if method := terminal.Detect(getenv); method != terminal.Text {
+		return method
+	}
+	return "terminal"
+}
+
+// openWindow creates the backend called o.backend, returning it together
+// with the keyboard that reads all of its input devices
+func openWindow(o windowOptions) (window, chip8.Keyboard, error) {
+	backend := o.backend
+	if backend == "auto" {
+		backend = chooseBackend(os.Getenv, runtime.GOOS)
+	}
+
+	keymap, err := loadKeymap(o.keymap)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := o.hotkeys.Check(keymap); err != nil {
+		return nil, nil, err
+	}
+
+	switch backend {
+	case "sdl":
+		return openSDL(o, keymap)
+	case "terminal", "tcell":
+		return openTerminal(o, keymap)
+	case terminal.Sixel, terminal.Kitty:
+		return openGraphics(backend, o, keymap)
+	}
+	return nil, nil, fmt.Errorf("unknown display %q", o.backend)
+}
+
+// sdlWindow also owns the gamepads, which use the SDL event queue of the display
+type sdlWindow struct {
+	*displays.NewSDLDisplay
+	gamepads *displays.Gamepads
+}
+
+func (w sdlWindow) Dispose() {
+	if w.gamepads != nil {
+		w.gamepads.Dispose()
+	}
+	w.NewSDLDisplay.Dispose()
+}
+
+func openSDL(o windowOptions, keymap input.Keymap) (window, chip8.Keyboard, error) {
+	scale := o.scale
+	if scale == 0 {
+		scale = 32
+	}
+	display, err := displays.NewSDLRenderer(int32(scale))
+	if err != nil {
+		return nil, nil, err
+	}
+	w := sdlWindow{NewSDLDisplay: display}
+
+	if err := configureVideo(display, o.screen, o.scaling); err != nil {
+		w.Dispose()
+		return nil, nil, err
+	}
+
+	if err := display.SetKeymap(keymap); err != nil {
+		w.Dispose()
+		return nil, nil, fmt.Errorf("%s: %s", o.keymap, err)
+	}
+	if err := display.SetHotkeys(o.hotkeys, o.onHotkey); err != nil {
+		w.Dispose()
+		return nil, nil, err
+	}
+
+	padmap, err := loadPadmap(o.padmap, o.romPath)
+	if err != nil {
+		w.Dispose()
+		return nil, nil, err
+	}
+	w.gamepads, err = displays.NewGamepads(padmap)
+	if err != nil {
+		w.Dispose()
+		return nil, nil, err
+	}
+	display.AddEventHandler(w.gamepads)
+
+	return w, input.Composite{display, w.gamepads}, nil
+}
+
+func openTerminal(o windowOptions, keymap input.Keymap) (window, chip8.Keyboard, error) {
+	encoder, err := terminal.ParseEncoder(o.cells)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	display, err := displays.NewTextDisplay()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := display.SetKeymap(keymap); err != nil {
+		display.Dispose()
+		return nil, nil, fmt.Errorf("%s: %s", o.keymap, err)
+	}
+	display.SetHotkeys(o.hotkeys, o.onHotkey)
+	display.SetEncoder(encoder)
+	display.SetPalette(o.screen.Palette)
+	display.SetPersistence(o.screen.Persistence)
+
+	return display, display, nil
+}
+
+func openGraphics(protocol string, o windowOptions, keymap input.Keymap) (window, chip8.Keyboard, error) {
+	scale := o.scale
+	if scale == 0 {
+		scale = 8
+	}
+	display, err := displays.NewGraphicsDisplay(protocol, o.screen, scale)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := display.SetKeymap(keymap); err != nil {
+		display.Dispose()
+		return nil, nil, fmt.Errorf("%s: %s", o.keymap, err)
+	}
+	display.SetHotkeys(o.hotkeys, o.onHotkey)
+
+	return display, display, nil
+}